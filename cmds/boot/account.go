@@ -0,0 +1,255 @@
+package boot
+
+import (
+	log "github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	DefaultAccount = "default"
+	OperateQueue   = 100 // 待发送操作队列长度
+	MaxRepost      = 3   // 被动单穿价被撤后最多重新下单次数
+)
+
+var (
+	accounts []*Account
+)
+
+type (
+	// 账户 行情共享 订单 持仓 保证金 和报价策略各自独立
+	Account struct {
+		Name     string
+		auth     *AuthConfig
+		conf     *AccountConfig
+		strategy Strategy
+
+		position    map[string]Position // 持仓推送加上其尚未包含的成交
+		fills       *fillTracker
+		order       []Order
+		tables      *Tables
+		partials    *partialBuffer
+		executions  *dedup
+		orderEvents *dedup
+		exits       *exitManager
+		amends      *amendTracker
+		legs        *hedgeManager // 价差做市的补腿
+		ledger      *Ledger
+		limiter     *rateLimiter
+		reposts     map[string]int // clOrdID -> 穿价被撤后已重新下单次数
+
+		marginMu sync.Mutex
+		margins  map[string]Margin
+		wallets  map[string]Wallet
+
+		haltMu sync.Mutex
+		halted string // 暂停原因 为空表示正常交易
+
+		operate chan Operate
+	}
+)
+
+func init() {
+	accounts = []*Account{NewAccount(&AccountConfig{Name: DefaultAccount})}
+}
+
+// NewAccount create account and start its order queue
+func NewAccount(c *AccountConfig) *Account {
+	a := newAccount(c)
+	go a.run()
+	return a
+}
+
+// newAccount create account without sending its queued operations
+func newAccount(c *AccountConfig) *Account {
+	a := &Account{
+		Name:        c.Name,
+		auth:        &AuthConfig{Key: c.Key, Secret: c.Secret},
+		conf:        c,
+		position:    make(map[string]Position),
+		fills:       newFillTracker(),
+		tables:      NewTables(),
+		partials:    newPartialBuffer(),
+		executions:  newDedup("", 10000),
+		orderEvents: newDedup("", 10000),
+		amends:      newAmendTracker(),
+		ledger:      NewLedger(),
+		limiter:     newRateLimiter(),
+		reposts:     make(map[string]int),
+		margins:     make(map[string]Margin),
+		wallets:     make(map[string]Wallet),
+		operate:     make(chan Operate, OperateQueue),
+	}
+	a.exits = newExitManager(a)
+	a.legs = newHedgeManager(func() *Hedge { return Conf.Basis.legHedge() })
+	return a
+}
+
+// newAccounts accounts of config
+func newAccounts() (list []*Account) {
+	for _, c := range Conf.AccountConfigs() {
+		a := NewAccount(c)
+		a.strategy = strategy()
+		list = append(list, a)
+	}
+	shareGlobal(list)
+	return
+}
+
+// accountOf account of stream, the only account when not multiplexed
+func accountOf(stream string) *Account {
+	if stream == "" && len(accounts) > 0 {
+		return accounts[0]
+	}
+	for _, a := range accounts {
+		if a.Name == stream {
+			return a
+		}
+	}
+	return nil
+}
+
+// run send queued operations one by one
+func (a *Account) run() {
+	for op := range a.operate {
+		switch op.Action {
+		case "create":
+			if err := exchange.PlaceOrder(a, op.Params); err != nil {
+				log.Info(err)
+			}
+		case "amend":
+			a.amend(op)
+		case "cancel":
+			if err := exchange.CancelOrder(a, op.Params); err != nil {
+				log.Info(err)
+			}
+		default:
+			log.Info("not supported action")
+		}
+	}
+}
+
+// sync load open orders and positions of account, table partials replace them once subscribed
+func (a *Account) sync() error {
+	orders, err := exchange.Orders(a)
+	if err != nil {
+		return err
+	}
+	positions, err := exchange.Positions(a)
+	if err != nil {
+		return err
+	}
+	a.setOrders(orders)
+	for _, p := range positions {
+		a.setPosition(p)
+	}
+	log.Infof("account %s has %d open orders and %d positions", a.Name, len(orders), len(positions))
+	return nil
+}
+
+// quoter strategy instance of account, configured strategy if not set
+func (a *Account) quoter() Strategy {
+	if a.strategy == nil {
+		return strategy()
+	}
+	return a.strategy
+}
+
+// trading trading parameters of account, global Trading if not overridden
+func (a *Account) trading() *Trading {
+	if a.conf.Trading == nil {
+		return Conf.Trading
+	}
+	return a.conf.Trading
+}
+
+// risk risk limits of account, global Risk if not overridden
+func (a *Account) risk() *Risk {
+	if a.conf.Risk == nil {
+		return Conf.Risk
+	}
+	return a.conf.Risk
+}
+
+// symbols traded symbols of all accounts, market data is subscribed once for them
+func symbols() (list []string) {
+	seen := make(map[string]bool)
+	add := func(s string) {
+		if s = strings.TrimSpace(s); s == "" || seen[s] {
+			return
+		}
+		seen[s] = true
+		list = append(list, s)
+	}
+	for _, a := range accounts {
+		for _, s := range a.trading().Symbol {
+			add(s)
+		}
+	}
+	add(Conf.Hedge.Symbol)
+	add(Conf.Basis.Quote)
+	add(Conf.Basis.Hedge)
+	return
+}
+
+// Halt stop trading account and cancel its working orders except exits protecting open positions, other accounts keep running
+func (a *Account) Halt(reason string) {
+	a.haltMu.Lock()
+	if a.halted != "" {
+		a.haltMu.Unlock()
+		return
+	}
+	a.halted = reason
+	a.haltMu.Unlock()
+
+	alert("account %s halted: %s", a.Name, reason)
+	for _, o := range a.order {
+		if !isWorking(o) || isExit(o.ClOrdID, o.Flags) {
+			continue
+		}
+		a.submit(Operate{"cancel", o.Symbol, map[string]interface{}{"orderID": o.OrderID}})
+	}
+}
+
+// Resume resume trading halted account
+func (a *Account) Resume() {
+	a.haltMu.Lock()
+	defer a.haltMu.Unlock()
+	if a.halted != "" {
+		log.Infof("account %s resumed", a.Name)
+	}
+	a.halted = ""
+}
+
+// Halted halt reason, empty if trading
+func (a *Account) Halted() string {
+	a.haltMu.Lock()
+	defer a.haltMu.Unlock()
+	return a.halted
+}
+
+// haltFile halt account while State.Dir/<name>.halt exists
+func (a *Account) haltFile() string {
+	return filepath.Join(Conf.State.Dir, a.Name+".halt")
+}
+
+// checkHalt halt or resume account by its halt file
+func (a *Account) checkHalt() {
+	_, err := os.Stat(a.haltFile())
+	switch {
+	case err == nil:
+		a.Halt("halt file " + a.haltFile())
+	case os.IsNotExist(err) && a.Halted() == "halt file "+a.haltFile():
+		a.Resume()
+	}
+}
+
+// stateDir processed events of default account stay in State.Dir
+func (a *Account) stateDir() string {
+	if a.Name == DefaultAccount {
+		return Conf.State.Dir
+	}
+	return filepath.Join(Conf.State.Dir, a.Name)
+}
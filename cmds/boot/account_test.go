@@ -0,0 +1,132 @@
+package boot
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAccountConfigs(t *testing.T) {
+	defer func() { Conf = Default() }()
+	dir, err := ioutil.TempDir("", "account")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.ini")
+	assert.Nil(t, ioutil.WriteFile(file, []byte(`
+[AuthConfig]
+Accounts = main, hedge
+
+[Trading]
+UnitQty = 100
+Symbol = XBTUSD
+
+[Risk]
+MaxLoss = 0.1
+
+[Account.main]
+Key = k1
+Secret = s1
+
+[Account.hedge]
+Key = k2
+Secret = s2
+Symbol = ETHUSD
+UnitQty = 10
+MaxLoss = 0.01
+`), 0644))
+
+	Conf = Default()
+	Conf.ConfigFile = file
+	assert.Nil(t, Conf.LoadFromIni())
+	configs := Conf.AccountConfigs()
+	assert.Len(t, configs, 2)
+	assert.Equal(t, "main", configs[0].Name)
+	assert.Equal(t, []string{"XBTUSD"}, configs[0].Trading.Symbol)
+	assert.Equal(t, 0.1, configs[0].Risk.MaxLoss)
+	assert.Equal(t, "k2", configs[1].Key)
+	assert.Equal(t, []string{"ETHUSD"}, configs[1].Trading.Symbol)
+	assert.Equal(t, 10.0, configs[1].Trading.UnitQty)
+	assert.Equal(t, 0.01, configs[1].Risk.MaxLoss)
+	assert.Equal(t, 100.0, Conf.Trading.UnitQty)
+
+	// 缺少账户段
+	assert.Nil(t, ioutil.WriteFile(file, []byte("[AuthConfig]\nAccounts = main\n"), 0644))
+	assert.NotNil(t, Conf.LoadFromIni())
+}
+
+func TestHaltAndRisks(t *testing.T) {
+	saved := accounts
+	defer func() {
+		accounts = saved
+		Conf = Default()
+	}()
+	dir, err := ioutil.TempDir("", "halt")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	Conf.State.Dir = dir
+
+	main := NewAccount(&AccountConfig{Name: "main"})
+	hedge := NewAccount(&AccountConfig{Name: "hedge", Trading: &Trading{Symbol: []string{"ETHUSD", "XBTUSD"}}})
+	accounts = []*Account{main, hedge}
+	assert.Equal(t, []string{"XBTUSD", "ETHUSD"}, symbols())
+
+	main.position["XBTUSD"] = Position{Symbol: "XBTUSD", CurrentQty: 300}
+	hedge.position["XBTUSD"] = Position{Symbol: "XBTUSD", CurrentQty: -200}
+	view := Risks()
+	assert.Len(t, view.Accounts, 2)
+	assert.Equal(t, 100.0, view.Position["XBTUSD"])
+
+	// 暂停一个账户不影响其他账户
+	assert.Nil(t, ioutil.WriteFile(hedge.haltFile(), nil, 0644))
+	main.checkHalt()
+	hedge.checkHalt()
+	assert.Empty(t, main.Halted())
+	assert.NotEmpty(t, hedge.Halted())
+	assert.NotEmpty(t, Risks().Accounts[1].Halted)
+
+	assert.Nil(t, os.Remove(hedge.haltFile()))
+	hedge.checkHalt()
+	assert.Empty(t, hedge.Halted())
+}
+
+func TestHaltKeepsStops(t *testing.T) {
+	defer func() {
+		Conf = Default()
+		orderBook10 = make(map[string]OrderBook10)
+	}()
+	Conf.Exit.StopType = "stop"
+	a := newAccount(&AccountConfig{Name: "test", Trading: &Trading{Symbol: []string{"XBTUSD"}, Spread: 2, PriceUint: 0.5, Range: 1}})
+	orderBook10["XBTUSD"] = OrderBook10{Symbol: "XBTUSD", Bids: []Bid{{6500, 100}}, Asks: []Ask{{6501, 100}}}
+
+	// 开仓 挂止盈止损
+	a.exits.onExecution(Execution{ExecID: "1", OrderID: "o1", ClOrdID: newClOrdID(PurposeQuote), Symbol: "XBTUSD", Side: "Buy", ExecType: "Trade", LastQty: 100, LastPx: 6500})
+	ops := drain(a)
+	assert.Len(t, ops, 2)
+	tp, sl := ops[0].Params, ops[1].Params
+	a.position["XBTUSD"] = Position{Symbol: "XBTUSD", CurrentQty: 100}
+	a.order = []Order{
+		{OrderID: "q", ClOrdID: newClOrdID(PurposeQuote), Symbol: "XBTUSD", Side: "Sell", Price: 6501, OrdStatus: "New"},
+		{OrderID: "tp", ClOrdID: tp["clOrdID"].(string), Symbol: "XBTUSD", Side: "Sell", Price: 6501, LeavesQty: 100, Flags: paramFlags(tp), OrdStatus: "New"},
+		{OrderID: "sl", ClOrdID: sl["clOrdID"].(string), Symbol: "XBTUSD", Side: "Sell", StopPx: 6490, LeavesQty: 100, Flags: paramFlags(sl), OrdStatus: "New"},
+	}
+
+	// 暂停只撤报价单 止盈止损保留
+	a.Halt("max loss")
+	assert.Equal(t, []Operate{{"cancel", "XBTUSD", map[string]interface{}{"orderID": "q"}}}, drain(a))
+
+	// 止损单消失后 暂停中仍重新挂出 新的报价单被丢弃
+	a.order = a.order[1:2]
+	for _, lot := range a.exits.lots {
+		lot.Placed = time.Time{}
+	}
+	a.ping()
+	ops = drain(a)
+	assert.Len(t, ops, 1)
+	assert.Equal(t, PurposeStopLoss, purposeOf(ops[0].Params["clOrdID"].(string)))
+	assert.Equal(t, 6490.0, ops[0].Params["stopPx"])
+	assert.False(t, a.submit(createOp("XBTUSD", Quote{"Buy", 6500, 100}, PurposeQuote)))
+}
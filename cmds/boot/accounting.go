@@ -0,0 +1,288 @@
+package boot
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/urfave/cli.v1"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+)
+
+const (
+	Satoshi = 1e8
+)
+
+type (
+	// 开仓批次 qty 带方向
+	openFill struct {
+		Qty   float64
+		Price float64
+	}
+
+	// 品种账目 金额以 XBT 计
+	SymbolLedger struct {
+		Symbol       string
+		Inverse      bool    // 反向合约 以 XBT 结算的美元合约
+		Multiplier   float64 // 合约乘数 每张合约每单位价格的 satoshi 反向合约为负
+		Position     float64 // 持仓 <0 (做空) >0(做多)
+		AvgCost      float64 // 平均开仓价
+		RealisedFifo float64 // 先进先出已实现盈亏
+		RealisedAvg  float64 // 平均成本已实现盈亏
+		Fees         float64 // 支付的 taker 手续费
+		Rebates      float64 // 收到的 maker 返佣
+		Funding      float64 // 资金费 >0 收取 <0 支付
+		Turnover     float64 // 成交合约数
+		Notional     float64 // 成交价值
+		LastPrice    float64
+
+		fifo []openFill
+		cost float64 // 平均成本法的持仓成本
+	}
+
+	// 账目 根据自身成交计算盈亏和费用
+	Ledger struct {
+		sync.Mutex
+		symbols map[string]*SymbolLedger
+	}
+)
+
+func NewLedger() *Ledger {
+	return &Ledger{symbols: make(map[string]*SymbolLedger)}
+}
+
+// isInverse contract quoted in USD and settled in XBT
+func isInverse(e Execution) bool {
+	return e.Currency == "USD" && e.SettlCurrency == "XBt"
+}
+
+// newSymbolLedger ledger of symbol, contract from instrument table, inferred from execution if not received
+func newSymbolLedger(e Execution) *SymbolLedger {
+	s := &SymbolLedger{Symbol: e.Symbol, Inverse: isInverse(e), Multiplier: -Satoshi}
+	if i, ok := instruments[e.Symbol]; ok && i.Multiplier != 0 {
+		s.Inverse, s.Multiplier = i.IsInverse, i.Multiplier
+	} else if !s.Inverse {
+		s.Multiplier = Satoshi
+	}
+	return s
+}
+
+// scale XBT per contract per price unit
+func (s *SymbolLedger) scale() float64 {
+	return math.Abs(s.Multiplier) / Satoshi
+}
+
+// raw value of qty contracts at price before multiplier
+func (s *SymbolLedger) raw(qty, price float64) float64 {
+	if s.Inverse {
+		return qty / price
+	}
+	return qty * price
+}
+
+// value of qty contracts at price, in XBT
+func (s *SymbolLedger) value(qty, price float64) float64 {
+	return s.raw(qty, price) * s.scale()
+}
+
+// pnl of closing qty (signed as the position) opened at entry and closed at exit, in XBT
+func (s *SymbolLedger) pnl(qty, entry, exit float64) float64 {
+	if s.Inverse {
+		return qty * (1/entry - 1/exit) * s.scale()
+	}
+	return qty * (exit - entry) * s.scale()
+}
+
+// Apply account own execution
+func (l *Ledger) Apply(e Execution) {
+	l.Lock()
+	defer l.Unlock()
+
+	s, ok := l.symbols[e.Symbol]
+	if !ok {
+		s = newSymbolLedger(e)
+		l.symbols[e.Symbol] = s
+	}
+
+	switch e.ExecType {
+	case "Funding":
+		s.Funding -= e.ExecComm / Satoshi
+		return
+	case "Trade":
+	default:
+		return
+	}
+	if e.LastQty <= 0 || e.LastPx <= 0 {
+		return
+	}
+
+	if e.ExecComm > 0 {
+		s.Fees += e.ExecComm / Satoshi
+	} else {
+		s.Rebates -= e.ExecComm / Satoshi
+	}
+	s.Turnover += e.LastQty
+	s.Notional += s.value(e.LastQty, e.LastPx)
+	s.LastPrice = e.LastPx
+
+	qty := e.LastQty
+	if e.Side == "Sell" {
+		qty = -qty
+	}
+	s.fill(qty, e.LastPx)
+}
+
+func (s *SymbolLedger) fill(qty, price float64) {
+	// 平仓部分
+	if s.Position != 0 && (s.Position > 0) != (qty > 0) {
+		closing := math.Min(math.Abs(qty), math.Abs(s.Position))
+		sign := math.Copysign(1, s.Position)
+
+		// 平均成本
+		s.RealisedAvg += s.pnl(sign*closing, s.AvgCost, price)
+		s.cost -= s.cost * closing / math.Abs(s.Position)
+
+		// 先进先出
+		left := closing
+		for left > 0 && len(s.fifo) > 0 {
+			f := &s.fifo[0]
+			n := math.Min(left, math.Abs(f.Qty))
+			s.RealisedFifo += s.pnl(sign*n, f.Price, price)
+			f.Qty -= sign * n
+			left -= n
+			if f.Qty == 0 {
+				s.fifo = s.fifo[1:]
+			}
+		}
+
+		s.Position += sign * -closing
+		qty += sign * closing
+		if s.Position == 0 {
+			s.AvgCost = 0
+			s.cost = 0
+		}
+	}
+
+	// 开仓部分
+	if qty != 0 {
+		s.fifo = append(s.fifo, openFill{qty, price})
+		s.Position += qty
+		s.cost += s.raw(math.Abs(qty), price)
+		if s.Inverse {
+			s.AvgCost = math.Abs(s.Position) / s.cost
+		} else {
+			s.AvgCost = s.cost / math.Abs(s.Position)
+		}
+	}
+}
+
+// Net realised pnl (fifo) after fees, rebates and funding
+func (s SymbolLedger) Net() float64 {
+	return s.RealisedFifo - s.Fees + s.Rebates + s.Funding
+}
+
+// Edge net pnl per contract traded
+func (s SymbolLedger) Edge() float64 {
+	if s.Turnover == 0 {
+		return 0
+	}
+	return s.Net() / s.Turnover
+}
+
+// USD value of amount in XBT at last price, false for non-inverse contracts settled in another quote currency
+func (s SymbolLedger) USD(amount float64) (float64, bool) {
+	if !s.Inverse {
+		return 0, false
+	}
+	return amount * s.LastPrice, true
+}
+
+// usd 格式化美元价值 非反向合约留空
+func (s SymbolLedger) usd(amount float64, format string) string {
+	v, ok := s.USD(amount)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf(format, v)
+}
+
+// Get copy of symbol ledger
+func (l *Ledger) Get(symbol string) (s SymbolLedger, ok bool) {
+	l.Lock()
+	defer l.Unlock()
+	v, ok := l.symbols[symbol]
+	if ok {
+		s = *v
+	}
+	return
+}
+
+// Symbols copies of all symbol ledgers sorted by symbol
+func (l *Ledger) Symbols() (symbols []SymbolLedger) {
+	l.Lock()
+	defer l.Unlock()
+	for _, v := range l.symbols {
+		symbols = append(symbols, *v)
+	}
+	sort.Slice(symbols, func(i, j int) bool { return symbols[i].Symbol < symbols[j].Symbol })
+	return
+}
+
+// Pnl print pnl of executions in journal
+func Pnl(c *cli.Context) (err error) {
+	if err = Conf.Load(c); err != nil {
+		return
+	}
+	if Conf.JournalConfig.Path == "" {
+		return fmt.Errorf("journal is not enabled, set JournalConfig.Path")
+	}
+	if _, err = os.Stat(Conf.JournalConfig.Path); err != nil {
+		return
+	}
+
+	j, err := OpenJournal(Conf.JournalConfig.Path)
+	if err != nil {
+		return
+	}
+	defer j.Close()
+
+	// 成交时记录的合约乘数
+	contracts, err := j.Contracts()
+	if err != nil {
+		return
+	}
+	for _, i := range contracts {
+		instruments[i.Symbol] = i
+	}
+
+	names := []string{c.String("account")}
+	if names[0] == "" {
+		if names, err = j.Accounts(); err != nil {
+			return
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "account\tsymbol\tposition\tavg cost\tfifo\tavg\tfees\trebates\tfunding\tnet\tnet usd\tturnover\tedge\tedge usd\t")
+	for _, name := range names {
+		executions, err := j.Executions(name, c.String("symbol"))
+		if err != nil {
+			return err
+		}
+
+		l := NewLedger()
+		for _, e := range executions {
+			l.Apply(e)
+		}
+		log.Infof("%d executions of account %s in %s", len(executions), name, Conf.JournalConfig.Path)
+
+		for _, s := range l.Symbols() {
+			fmt.Fprintf(w, "%s\t%s\t%v\t%.2f\t%.8f\t%.8f\t%.8f\t%.8f\t%.8f\t%.8f\t%s\t%v\t%.8f\t%s\t\n",
+				name, s.Symbol, s.Position, s.AvgCost, s.RealisedFifo, s.RealisedAvg, s.Fees, s.Rebates, s.Funding,
+				s.Net(), s.usd(s.Net(), "%.2f"), s.Turnover, s.Edge(), s.usd(s.Edge(), "%.4f"))
+		}
+	}
+	return w.Flush()
+}
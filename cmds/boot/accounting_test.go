@@ -0,0 +1,83 @@
+package boot
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func trade(side string, qty, price, comm float64) Execution {
+	return Execution{
+		Symbol:        "XBTUSD",
+		Side:          side,
+		ExecType:      "Trade",
+		LastQty:       qty,
+		LastPx:        price,
+		ExecComm:      comm,
+		Currency:      "USD",
+		SettlCurrency: "XBt",
+	}
+}
+
+func TestLedger(t *testing.T) {
+	l := NewLedger()
+	l.Apply(trade("Buy", 100, 100, -25000))
+	l.Apply(trade("Buy", 100, 200, -12500))
+	l.Apply(trade("Sell", 150, 150, 75000))
+	l.Apply(Execution{Symbol: "XBTUSD", ExecType: "Funding", ExecComm: 10000})
+
+	s, ok := l.Get("XBTUSD")
+	assert.True(t, ok)
+	assert.True(t, s.Inverse)
+	assert.Equal(t, 50.0, s.Position)
+	assert.Equal(t, 350.0, s.Turnover)
+
+	// fifo closes 100@100 and 50@200
+	assert.InDelta(t, 100*(1/100.0-1/150.0)+50*(1/200.0-1/150.0), s.RealisedFifo, 1e-12)
+	// average cost is the harmonic mean of entries
+	assert.InDelta(t, 200/1.5, s.AvgCost, 1e-9)
+	assert.InDelta(t, 150*(1.5/200-1/150.0), s.RealisedAvg, 1e-12)
+	assert.InDelta(t, 0.00075, s.Fees, 1e-12)
+	assert.InDelta(t, 0.000375, s.Rebates, 1e-12)
+	assert.InDelta(t, -0.0001, s.Funding, 1e-12)
+	assert.InDelta(t, s.RealisedFifo-0.00075+0.000375-0.0001, s.Net(), 1e-12)
+	usd, ok := s.USD(1)
+	assert.True(t, ok)
+	assert.Equal(t, 150.0, usd)
+
+	// flip to short
+	l.Apply(trade("Sell", 100, 150, 0))
+	s, _ = l.Get("XBTUSD")
+	assert.Equal(t, -50.0, s.Position)
+	assert.InDelta(t, 150.0, s.AvgCost, 1e-9)
+}
+
+func TestLedgerMultiplier(t *testing.T) {
+	defer func() { instruments = make(map[string]Instrument) }()
+	instruments["ETHUSD"] = Instrument{Symbol: "ETHUSD", Multiplier: 100}
+	instruments["ETHZ18"] = Instrument{Symbol: "ETHZ18", Multiplier: 100000000}
+
+	// quanto 每张每美元 100 satoshi
+	l := NewLedger()
+	eth := trade("Buy", 10, 200, 0)
+	eth.Symbol = "ETHUSD"
+	l.Apply(eth)
+	eth.Side, eth.LastPx = "Sell", 210
+	l.Apply(eth)
+	s, _ := l.Get("ETHUSD")
+	assert.False(t, s.Inverse)
+	assert.InDelta(t, 10*10*100/Satoshi, s.RealisedFifo, 1e-12)
+	assert.InDelta(t, 10*(200+210)*100/Satoshi, s.Notional, 1e-12)
+	// 非反向合约不折算美元
+	_, ok := s.USD(s.Net())
+	assert.False(t, ok)
+	assert.Equal(t, "", s.usd(s.Net(), "%.2f"))
+
+	// 以 XBT 报价的线性合约
+	fut := Execution{Symbol: "ETHZ18", Side: "Buy", ExecType: "Trade", LastQty: 2, LastPx: 0.03, Currency: "XBT", SettlCurrency: "XBt"}
+	l.Apply(fut)
+	fut.Side, fut.LastPx = "Sell", 0.031
+	l.Apply(fut)
+	s, _ = l.Get("ETHZ18")
+	assert.InDelta(t, 0.002, s.RealisedFifo, 1e-12)
+	assert.InDelta(t, 0.0, s.AvgCost, 1e-12)
+}
@@ -0,0 +1,140 @@
+package boot
+
+import (
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+type (
+	// 已发出 尚未在 order 表中确认的改单
+	pendingAmend struct {
+		Order Order
+		Price float64
+		Qty   float64
+		Sent  time.Time
+	}
+
+	amendTracker struct {
+		sync.Mutex
+		pending map[string]pendingAmend
+	}
+)
+
+func newAmendTracker() *amendTracker {
+	return &amendTracker{pending: make(map[string]pendingAmend)}
+}
+
+// amendTimeout amends not acked in time are considered lost
+func amendTimeout() time.Duration {
+	return 2 * time.Duration(Conf.Trading.Watch) * time.Second
+}
+
+// sent record amend of o, price and leavesQty not in params stay unchanged
+func (t *amendTracker) sent(o Order, params map[string]interface{}, now time.Time) {
+	p := pendingAmend{o, o.Price, o.LeavesQty, now}
+	if v, ok := params["price"].(float64); ok {
+		p.Price = v
+	}
+	if v, ok := params["leavesQty"].(float64); ok {
+		p.Qty = v
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	t.pending[o.OrderID] = p
+}
+
+// ack check order update against pending amend
+func (t *amendTracker) ack(o Order) {
+	t.Lock()
+	defer t.Unlock()
+	p, ok := t.pending[o.OrderID]
+	if !ok {
+		return
+	}
+	if !isWorking(o) {
+		delete(t.pending, o.OrderID)
+		return
+	}
+	if o.Price == p.Price && o.LeavesQty == p.Qty {
+		delete(t.pending, o.OrderID)
+		log.Infof("%s order %s amended to %v, qty is %v", o.Side, o.OrderID, o.Price, o.LeavesQty)
+	}
+}
+
+// fail drop pending amend
+func (t *amendTracker) fail(orderID string) (p pendingAmend, ok bool) {
+	t.Lock()
+	defer t.Unlock()
+	p, ok = t.pending[orderID]
+	delete(t.pending, orderID)
+	return
+}
+
+// apply working orders as they will be once pending amends are acked
+func (t *amendTracker) apply(working []Order, now time.Time) []Order {
+	t.Lock()
+	defer t.Unlock()
+	orders := make([]Order, len(working))
+	for i, o := range working {
+		orders[i] = o
+		p, ok := t.pending[o.OrderID]
+		if !ok {
+			continue
+		}
+		if now.Sub(p.Sent) > amendTimeout() {
+			log.Warnf("%s order %s amend not acked in %v", o.Side, o.OrderID, amendTimeout())
+			delete(t.pending, o.OrderID)
+			continue
+		}
+		orders[i].Price = p.Price
+		orders[i].LeavesQty = p.Qty
+	}
+	return orders
+}
+
+// findOrder find order by orderID
+func (a *Account) findOrder(orderID string) (Order, bool) {
+	for _, v := range a.order {
+		if v.OrderID == orderID {
+			return v, true
+		}
+	}
+	return Order{}, false
+}
+
+// trackAmend record queued amend of working order, on the event loop where the order table is updated
+func (a *Account) trackAmend(op Operate) {
+	orderID, _ := op.Params["orderID"].(string)
+	if o, ok := a.findOrder(orderID); ok {
+		a.amends.sent(o, op.Params, time.Now())
+	}
+}
+
+// 改单 在发送队列中执行 原订单取自入队时记录的 pending 不读订单表
+func (a *Account) amend(op Operate) {
+	err := exchange.AmendOrder(a, op.Params)
+	if err == nil {
+		return
+	}
+	log.Info(err)
+
+	orderID, _ := op.Params["orderID"].(string)
+	p, ok := a.amends.fail(orderID)
+	if !ok {
+		return
+	}
+
+	// 改单失败 撤单后重新下单 新单替换原订单 不再检查保证金
+	params := make(map[string]interface{})
+	params["orderID"] = p.Order.OrderID
+	a.enqueue(Operate{"cancel", op.Symbol, params})
+
+	purpose := purposeOf(p.Order.ClOrdID)
+	if purpose == "" {
+		purpose = PurposeQuote
+	}
+	a.enqueue(createOp(op.Symbol, Quote{p.Order.Side, p.Price, p.Qty}, purpose))
+	log.Infof("%s order %s replaced by new order at %v, qty is %v", p.Order.Side, p.Order.OrderID, p.Price, p.Qty)
+}
@@ -0,0 +1,59 @@
+package boot
+
+import (
+	"math"
+)
+
+type (
+	// Avellaneda-Stoikov 最优做市
+	// 保留价 r = s - q*γ*σ²*τ
+	// 最优价差 δ = γ*σ²*τ + 2/γ*ln(1+γ/k)
+	// q 为以 UnitQty 计的持仓 σ 为中间价波动率 k 为成交强度衰减系数 τ 为滚动时间窗口
+	avellanedaStrategy struct{}
+)
+
+// 成交强度取自成交数据
+func (avellanedaStrategy) usesTrades() bool {
+	return true
+}
+
+func (avellanedaStrategy) Quotes(snap Snapshot) []Quote {
+	sigma := snap.Signals.Volatility
+	k := snap.Signals.Intensity
+	if sigma <= 0 || k <= 0 || Conf.Quoting.RiskAversion <= 0 {
+		// 数据不足 暂不报价
+		return nil
+	}
+
+	bid, ask := avellaneda(snap, sigma, k)
+	unit := snap.trading().UnitQty
+	return []Quote{
+		{"Buy", bid, unit},
+		{"Sell", ask, unit},
+	}
+}
+
+// avellaneda bid and ask price, rounded to tick away from the reservation price and kept passive
+func avellaneda(snap Snapshot, sigma, k float64) (bid, ask float64) {
+	book := snap.Book
+	t := snap.trading()
+	tick := t.PriceUint
+	gamma := Conf.Quoting.RiskAversion
+	tau := float64(Conf.Quoting.Horizon)
+
+	mid := (book.Bids[0][0] + book.Asks[0][0]) / 2
+	if fair, ok := fairValue(snap); ok {
+		mid = fair
+	}
+	q := snap.Position.CurrentQty / t.UnitQty
+
+	reservation := mid - q*gamma*sigma*sigma*tau
+	spread := math.Max(gamma*sigma*sigma*tau+2/gamma*math.Log(1+gamma/k), tick)
+
+	bid = math.Floor((reservation-spread/2)/tick) * tick
+	ask = math.Ceil((reservation+spread/2)/tick) * tick
+
+	bid = math.Min(bid, book.Asks[0][0]-tick)
+	ask = math.Max(ask, book.Bids[0][0]+tick)
+	return
+}
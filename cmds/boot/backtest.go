@@ -0,0 +1,214 @@
+package boot
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/urfave/cli.v1"
+	"math"
+	"os"
+	"time"
+)
+
+type (
+	// 回测 按录制的 websocket 消息回放行情
+	// 成交按对手方成交价穿过挂单价撮合 合约按反向合约(XBTUSD)计算盈亏
+	backtest struct {
+		strategy Strategy
+		watch    time.Duration
+		books    map[string]OrderBook10
+		signals  map[string]*Signals
+		basis    *basisTracker
+		position map[string]float64
+		cash     map[string]float64
+		quotes   map[string][]Quote
+		last     map[string]time.Time
+		fills    map[string]int
+		volume   map[string]float64
+	}
+
+	BacktestResult struct {
+		Symbol   string
+		Fills    int
+		Volume   float64
+		Position float64
+		Pnl      float64
+	}
+)
+
+func newBacktest(s Strategy) *backtest {
+	return &backtest{
+		strategy: s,
+		watch:    time.Duration(Conf.Trading.Watch) * time.Second,
+		books:    make(map[string]OrderBook10),
+		signals:  make(map[string]*Signals),
+		basis:    newBasisTracker(time.Duration(Conf.Basis.Window) * time.Second),
+		position: make(map[string]float64),
+		cash:     make(map[string]float64),
+		quotes:   make(map[string][]Quote),
+		last:     make(map[string]time.Time),
+		fills:    make(map[string]int),
+		volume:   make(map[string]float64),
+	}
+}
+
+// Backtest replay recorded frames through the configured strategy
+func Backtest(c *cli.Context) (err error) {
+	if err = Conf.Load(c); err != nil {
+		return
+	}
+
+	log.SetLevel(log.InfoLevel)
+	if Conf.Debug {
+		log.SetLevel(log.DebugLevel)
+	}
+
+	if c.String("file") == "" {
+		return fmt.Errorf("recorded frames file required")
+	}
+	f, err := os.Open(c.String("file"))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	bt := newBacktest(strategy())
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for scanner.Scan() {
+		if err := bt.feed(scanner.Bytes()); err != nil {
+			log.Debug(err)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return
+	}
+
+	for _, r := range bt.results() {
+		log.Infof("%s fills: %d, volume: %v, position: %v, pnl: %.8f XBT", r.Symbol, r.Fills, r.Volume, r.Position, r.Pnl)
+	}
+	return
+}
+
+func (bt *backtest) signalsFor(symbol string) *Signals {
+	s, ok := bt.signals[symbol]
+	if !ok {
+		s = newSignals(time.Duration(Conf.Quoting.Window) * time.Second)
+		bt.signals[symbol] = s
+	}
+	return s
+}
+
+// feed one recorded frame
+func (bt *backtest) feed(msg []byte) (err error) {
+	switch string(tableOf(msg)) {
+	case "orderBook10":
+		return decodeOrderBook10(msg, func(obm *OrderBook10Msg) {
+			for _, v := range obm.Data {
+				t := parseTime(v.Timestamp)
+				book := bt.books[v.Symbol]
+				book.CopyFrom(v)
+				bt.books[v.Symbol] = book
+				bt.signalsFor(book.Symbol).OnBook(book, t)
+				bt.basis.OnBook(book, t)
+				bt.requote(book.Symbol, t)
+			}
+		})
+	case "trade":
+		tm := &TradeMsg{}
+		if err = json.Unmarshal(msg, tm); err != nil {
+			return
+		}
+		for _, trade := range tm.Data {
+			bt.signalsFor(trade.Symbol).OnTrade(trade, parseTime(trade.Timestamp))
+			bt.match(trade)
+		}
+	}
+	return
+}
+
+// requote replace working quotes every watch interval
+func (bt *backtest) requote(symbol string, t time.Time) {
+	if t.Sub(bt.last[symbol]) < bt.watch {
+		return
+	}
+	bt.last[symbol] = t
+
+	book := bt.books[symbol]
+	if len(book.Bids) == 0 || len(book.Asks) == 0 {
+		return
+	}
+
+	if math.Abs(bt.position[symbol]) > Conf.Trading.MaxHoldQty {
+		bt.quotes[symbol] = nil
+		return
+	}
+
+	positions := make(map[string]Position)
+	for k, v := range bt.position {
+		positions[k] = Position{Symbol: k, CurrentQty: v}
+	}
+	snap := Snapshot{
+		symbol,
+		t,
+		book,
+		Position{Symbol: symbol, CurrentQty: bt.position[symbol]},
+		bt.signalsFor(symbol).Stats(t),
+		Instrument{},
+		Instrument{},
+		NextFunding{},
+		Conf.Trading,
+		positions,
+		bt.basis.Stats(),
+	}
+	bt.quotes[symbol] = bt.strategy.Quotes(snap)
+}
+
+// match trade against working quotes
+func (bt *backtest) match(trade Trade) {
+	quotes := bt.quotes[trade.Symbol]
+	size := trade.Size
+	for i, q := range quotes {
+		if q.Qty <= 0 || size <= 0 {
+			continue
+		}
+		if !(trade.Side == "Sell" && q.Side == "Buy" && trade.Price <= q.Price) &&
+			!(trade.Side == "Buy" && q.Side == "Sell" && trade.Price >= q.Price) {
+			continue
+		}
+
+		qty := math.Min(q.Qty, size)
+		size -= qty
+		quotes[i].Qty -= qty
+
+		if q.Side == "Buy" {
+			bt.position[trade.Symbol] += qty
+			bt.cash[trade.Symbol] += qty / q.Price
+		} else {
+			bt.position[trade.Symbol] -= qty
+			bt.cash[trade.Symbol] -= qty / q.Price
+		}
+		bt.fills[trade.Symbol]++
+		bt.volume[trade.Symbol] += qty
+		log.Debugf("%s %s filled at %v, qty is %v", trade.Symbol, q.Side, q.Price, qty)
+	}
+}
+
+// results mark to market by latest mid
+func (bt *backtest) results() (r []BacktestResult) {
+	for symbol, book := range bt.books {
+		if len(book.Bids) == 0 || len(book.Asks) == 0 {
+			continue
+		}
+		mid := (book.Bids[0][0] + book.Asks[0][0]) / 2
+		r = append(r, BacktestResult{
+			symbol,
+			bt.fills[symbol],
+			bt.volume[symbol],
+			bt.position[symbol],
+			bt.cash[symbol] - bt.position[symbol]/mid,
+		})
+	}
+	return
+}
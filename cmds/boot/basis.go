@@ -0,0 +1,157 @@
+package boot
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+var (
+	basis *basisTracker
+)
+
+type (
+	// 基差 = 报价腿中间价 - 对冲腿中间价 按 Window 秒时间加权的指数均值作为公允基差
+	basisTracker struct {
+		sync.Mutex
+		window  time.Duration
+		mids    map[string]float64
+		fair    float64
+		current float64
+		last    time.Time
+		samples int
+	}
+
+	BasisStats struct {
+		Fair     float64 // 公允基差
+		Current  float64 // 最新基差
+		HedgeMid float64 // 对冲腿中间价
+		Ready    bool    // 两条腿都有盘口
+	}
+
+	// 跨期价差做市 只在报价腿上按 对冲腿中间价 + 公允基差 报价 成交后由 legs 在对冲腿补腿
+	basisStrategy struct{}
+)
+
+func init() {
+	basis = newBasisTracker(0)
+}
+
+func newBasisTracker(window time.Duration) *basisTracker {
+	return &basisTracker{window: window, mids: make(map[string]float64)}
+}
+
+// legHedge hedge config of the leg-in order of basis quoting
+func (b *Basis) legHedge() *Hedge {
+	return &Hedge{
+		Symbol:      b.Hedge,
+		Ratio:       1,
+		Threshold:   1,
+		Slippage:    b.Slippage,
+		Timeout:     b.Timeout,
+		MaxUnhedged: b.MaxUnhedged,
+	}
+}
+
+// OnBook update mid of leg and basis
+func (t *basisTracker) OnBook(book OrderBook10, now time.Time) {
+	c := Conf.Basis
+	if c.Quote == "" || (book.Symbol != c.Quote && book.Symbol != c.Hedge) || len(book.Bids) == 0 || len(book.Asks) == 0 {
+		return
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	t.mids[book.Symbol] = (book.Bids[0][0] + book.Asks[0][0]) / 2
+	quote, ok1 := t.mids[c.Quote]
+	hedge, ok2 := t.mids[c.Hedge]
+	if !ok1 || !ok2 {
+		return
+	}
+
+	t.current = quote - hedge
+	window := t.window
+	if window <= 0 {
+		window = time.Duration(c.Window) * time.Second
+	}
+	if t.samples == 0 || window <= 0 {
+		t.fair = t.current
+	} else if dt := now.Sub(t.last); dt > 0 {
+		alpha := 1 - math.Exp(-float64(dt)/float64(window))
+		t.fair += alpha * (t.current - t.fair)
+	}
+	t.last = now
+	t.samples++
+}
+
+// Stats basis of legs
+func (t *basisTracker) Stats() BasisStats {
+	t.Lock()
+	defer t.Unlock()
+	return BasisStats{
+		Fair:     t.fair,
+		Current:  t.current,
+		HedgeMid: t.mids[Conf.Basis.Hedge],
+		Ready:    t.samples > 0,
+	}
+}
+
+// 报价腿 买卖价以 对冲腿中间价 + 公允基差 为中心 单腿敞口不超过 MaxLegQty
+func (basisStrategy) Quotes(snap Snapshot) (quotes []Quote) {
+	c := Conf.Basis
+	if snap.Symbol != c.Quote || !snap.Basis.Ready || snap.Basis.HedgeMid <= 0 {
+		return
+	}
+
+	book := snap.Book
+	t := snap.trading()
+	tick := t.PriceUint
+	fair := snap.Basis.HedgeMid + snap.Basis.Fair
+	half := c.Spread * tick
+
+	bid := math.Min(math.Floor((fair-half)/tick)*tick, book.Asks[0][0]-tick)
+	ask := math.Max(math.Ceil((fair+half)/tick)*tick, book.Bids[0][0]+tick)
+
+	// 单腿敞口 报价腿持仓加对冲腿持仓
+	unhedged := snap.Position.CurrentQty + snap.Positions[c.Hedge].CurrentQty
+	buy, sell := t.UnitQty, t.UnitQty
+	if c.MaxLegQty > 0 {
+		buy = math.Min(buy, c.MaxLegQty-unhedged)
+		sell = math.Min(sell, c.MaxLegQty+unhedged)
+	}
+	if buy > 0 {
+		quotes = append(quotes, Quote{"Buy", bid, buy})
+	}
+	if sell > 0 {
+		quotes = append(quotes, Quote{"Sell", ask, sell})
+	}
+	return
+}
+
+// basisQuoting whether account quotes basis
+func (a *Account) basisQuoting() bool {
+	_, ok := a.quoter().(basisStrategy)
+	return ok && Conf.Basis.Quote != "" && Conf.Basis.Hedge != ""
+}
+
+// basisLeg position of symbol is a leg of basis quoting, sized by MaxLegQty and legIn rather than exits and unwind
+func (a *Account) basisLeg(symbol string) bool {
+	return a.basisQuoting() && (symbol == Conf.Basis.Quote || symbol == Conf.Basis.Hedge)
+}
+
+// legIn hedge filled quote leg on hedge leg
+func (a *Account) legIn(now time.Time) {
+	if !a.basisQuoting() {
+		return
+	}
+	a.legs.run(a, a.position[Conf.Basis.Quote].CurrentQty, now)
+}
+
+// checkLegs stop quoting quote leg while legged longer than MaxUnhedged
+func (a *Account) checkLegs(symbol string, now time.Time) bool {
+	if !a.basisQuoting() || symbol != Conf.Basis.Quote || !a.legs.overdue(now) {
+		return true
+	}
+	alert("%s %s legged for more than %ds, stop quoting", a.Name, symbol, Conf.Basis.MaxUnhedged)
+	return false
+}
@@ -0,0 +1,67 @@
+package boot
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestBasis(t *testing.T) {
+	defer func() { Conf = Default() }()
+	Conf.Basis.Quote = "XBTZ18"
+	Conf.Basis.Hedge = "XBTUSD"
+
+	now := time.Now()
+	tracker := newBasisTracker(time.Minute)
+	tracker.OnBook(OrderBook10{Symbol: "XBTUSD", Bids: []Bid{{6500, 1}}, Asks: []Ask{{6501, 1}}}, now)
+	assert.False(t, tracker.Stats().Ready)
+	tracker.OnBook(OrderBook10{Symbol: "XBTZ18", Bids: []Bid{{6600, 1}}, Asks: []Ask{{6601, 1}}}, now)
+	assert.Equal(t, BasisStats{100, 100, 6500.5, true}, tracker.Stats())
+	tracker.OnBook(OrderBook10{Symbol: "XBTZ18", Bids: []Bid{{6620, 1}}, Asks: []Ask{{6621, 1}}}, now.Add(time.Minute))
+	assert.InDelta(t, 112.64, tracker.Stats().Fair, 0.01)
+	assert.Equal(t, 120.0, tracker.Stats().Current)
+
+	snap := Snapshot{
+		Symbol:    "XBTZ18",
+		Book:      OrderBook10{Bids: []Bid{{6600, 1}}, Asks: []Ask{{6601, 1}}},
+		Position:  Position{CurrentQty: 450},
+		Trading:   &Trading{UnitQty: 100, PriceUint: 0.5},
+		Positions: map[string]Position{},
+		Basis:     BasisStats{Fair: 100, HedgeMid: 6500.5, Ready: true},
+	}
+	// 单腿敞口 450 买单只剩 50
+	assert.Equal(t, []Quote{{"Buy", 6598, 50}, {"Sell", 6603, 100}}, basisStrategy{}.Quotes(snap))
+
+	// 已补腿
+	snap.Positions["XBTUSD"] = Position{CurrentQty: -450}
+	assert.Equal(t, []Quote{{"Buy", 6598, 100}, {"Sell", 6603, 100}}, basisStrategy{}.Quotes(snap))
+
+	snap.Symbol = "XBTUSD"
+	assert.Empty(t, basisStrategy{}.Quotes(snap))
+
+	// 补腿超时停止报价
+	a := NewAccount(&AccountConfig{Name: "test"})
+	a.strategy = basisStrategy{}
+	delta, _ := a.legs.exposure(a, 100, now)
+	assert.Equal(t, 100.0, delta)
+	assert.True(t, a.checkLegs("XBTZ18", now.Add(time.Second)))
+	assert.False(t, a.checkLegs("XBTZ18", now.Add(time.Minute)))
+	assert.True(t, a.checkLegs("XBTUSD", now.Add(time.Minute)))
+
+	// 补腿后恢复
+	a.position["XBTUSD"] = Position{Symbol: "XBTUSD", CurrentQty: -100}
+	delta, _ = a.legs.exposure(a, 100, now.Add(time.Minute))
+	assert.Equal(t, 0.0, delta)
+	assert.True(t, a.checkLegs("XBTZ18", now.Add(time.Minute)))
+
+	// 报价腿成交不挂止盈止损 超出 MaxHoldQty 不单独减仓
+	defer func() { orderBook10 = make(map[string]OrderBook10) }()
+	orderBook10["XBTZ18"] = OrderBook10{Symbol: "XBTZ18", Bids: []Bid{{6600, 1}}, Asks: []Ask{{6601, 1}}}
+	leg := newAccount(&AccountConfig{Name: "leg", Trading: &Trading{Symbol: []string{"XBTZ18"}, UnitQty: 100, MaxHoldQty: 1000, PriceUint: 0.5}})
+	leg.strategy = basisStrategy{}
+	leg.exits.onExecution(Execution{ExecID: "1", OrderID: "o1", ClOrdID: newClOrdID(PurposeQuote), Symbol: "XBTZ18", Side: "Buy", ExecType: "Trade", LastQty: 2000, LastPx: 6600})
+	assert.Empty(t, leg.exits.lots)
+	leg.position["XBTZ18"] = Position{Symbol: "XBTZ18", CurrentQty: 2000}
+	leg.ping()
+	assert.Empty(t, drain(leg))
+}
@@ -0,0 +1,329 @@
+package boot
+
+import (
+	"fmt"
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	AuthExpire = 7 * 24 * 60 * 60
+)
+
+var (
+	// 订单标记对应的 execInst
+	bitmexExecInst = map[string]string{
+		FlagPostOnly:   "ParticipateDoNotInitiate",
+		FlagReduceOnly: "ReduceOnly",
+		FlagClose:      "Close",
+		FlagLastPrice:  "LastPrice",
+		FlagMarkPrice:  "MarkPrice",
+		FlagIndexPrice: "IndexPrice",
+	}
+
+	bitmexOrdType = map[string]string{
+		OrdLimit:     "Limit",
+		OrdStop:      "Stop",
+		OrdStopLimit: "StopLimit",
+	}
+)
+
+type (
+	CMD struct {
+		Command string        `json:"op"`
+		Args    []interface{} `json:"args"`
+	}
+
+	// BitMEX 适配 websocket 接收行情和账户数据 REST 下单
+	bitmex struct {
+		conn     *websocket.Conn
+		sc       *streamConn
+		done     chan struct{}
+		record   io.Writer
+		accounts []*Account
+		session  *Session       // 认证和订阅状态
+		partials *partialBuffer // 行情表 partial 前的增量
+	}
+)
+
+func newBitmex() Exchange {
+	return &bitmex{session: NewSession(), partials: newPartialBuffer()}
+}
+
+func (b *bitmex) Name() string {
+	return "bitmex"
+}
+
+// Record write received frames to w
+func (b *bitmex) Record(w io.Writer) {
+	b.record = w
+}
+
+// Connect dial websocket and authenticate accounts, each on its own stream when multiplexed
+func (b *bitmex) Connect(accounts []*Account) (err error) {
+	if len(accounts) > 1 && !multiplexed() {
+		return fmt.Errorf("%d accounts configured, use the multiplexed endpoint /realtimemd", len(accounts))
+	}
+	b.accounts = accounts
+
+	u := url.URL{Scheme: Conf.WSConfig.Scheme, Host: Conf.WSConfig.Host, Path: Conf.WSConfig.Path}
+	log.Infof("connecting to %s", u.String())
+
+	conn, resp, err := websocket.DefaultDialer.Dial(u.String(), nil)
+	if err != nil {
+		return err
+	}
+	log.Info(resp.Header)
+	b.conn = conn
+	b.sc = &streamConn{conn: conn, multiplexed: multiplexed()}
+	b.done = make(chan struct{})
+
+	b.session = NewSession()
+	b.partials = newPartialBuffer()
+
+	// receive message
+	go func() {
+		defer close(b.done)
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				log.Error("read:", err)
+				return
+			}
+			if b.record != nil {
+				b.record.Write(append(message, '\n'))
+			}
+			if err := b.dispatch(message); err != nil {
+				log.Error(err)
+			}
+		}
+	}()
+
+	// Auth
+	auth := func(stream string, c *AuthConfig) error {
+		b.session.Expect(stream, nil, true)
+		expires := time.Now().Unix() + int64(AuthExpire)
+		sign := HmacSha256([]byte(c.Secret), []byte(fmt.Sprintf("%s%d", "GET/realtime", expires)))
+		return b.sc.send(stream, CMD{
+			Command: "authKeyExpires",
+			Args:    []interface{}{c.Key, expires, sign},
+		})
+	}
+
+	if !b.sc.multiplexed {
+		return auth("", accounts[0].auth)
+	}
+	if err := b.sc.open(MarketStream); err != nil {
+		return err
+	}
+	for _, a := range accounts {
+		if err := b.sc.open(a.Name); err != nil {
+			return err
+		}
+		if err := auth(a.Name, a.auth); err != nil {
+			return err
+		}
+	}
+	return
+}
+
+// Subscribe subscribe market data of symbols and private tables of accounts, wait for confirmation
+func (b *bitmex) Subscribe(symbols []string) error {
+	subscribe := func(stream string, topics []string) error {
+		b.session.Expect(stream, topics, false)
+		for _, topic := range topics {
+			retryLimit := 3
+		Retry:
+			if retryLimit <= 0 {
+				return fmt.Errorf("subscribe %v failed", topic)
+			}
+			if err := b.sc.send(stream, CMD{Command: "subscribe", Args: []interface{}{topic}}); err != nil {
+				log.Error(err)
+				time.Sleep(time.Second * 1)
+				retryLimit--
+				goto Retry
+			}
+		}
+		return nil
+	}
+
+	if b.sc.multiplexed {
+		public, private := splitTopics(topics(symbols))
+		if err := subscribe(MarketStream, public); err != nil {
+			return err
+		}
+		for _, a := range b.accounts {
+			if err := subscribe(a.Name, private); err != nil {
+				return err
+			}
+		}
+	} else if err := subscribe("", topics(symbols)); err != nil {
+		return err
+	}
+
+	// wait for auth and subscriptions before trading
+	select {
+	case <-b.session.Ready():
+		log.Info("all topics subscribed")
+	case err := <-b.session.Failed():
+		return err
+	case <-b.done:
+		return fmt.Errorf("connection closed before subscribed")
+	case <-time.After(time.Second * SubscribeTimeout):
+		return fmt.Errorf("subscribe timeout, pending %v", b.session.Pending())
+	}
+	return nil
+}
+
+// Serve ping every Watch seconds, account tasks run on pong
+func (b *bitmex) Serve(stop <-chan struct{}) error {
+	defer b.conn.Close()
+
+	ticker := time.NewTicker(time.Second * time.Duration(Conf.Trading.Watch))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.done:
+			return nil
+		case err := <-b.session.Failed():
+			return err
+		case <-ticker.C:
+			if err := b.sc.ping(); err != nil {
+				log.Error("write:", err)
+				return err
+			}
+		case <-stop:
+			// Cleanly close the connection by sending a close message and then
+			// waiting (with timeout) for the server to close the connection.
+			b.sc.Lock()
+			err := b.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			b.sc.Unlock()
+			if err != nil {
+				log.Error("write close:", err)
+				return err
+			}
+			select {
+			case <-b.done:
+			case <-time.After(time.Second):
+			}
+			return nil
+		}
+	}
+}
+
+// 下单
+func (b *bitmex) PlaceOrder(a *Account, params map[string]interface{}) error {
+	return b.do(a, "POST", "/order", bitmexParams(params), &OrderResponse{})
+}
+
+// 改单
+func (b *bitmex) AmendOrder(a *Account, params map[string]interface{}) error {
+	return b.do(a, "PUT", "/order", bitmexParams(params), &OrderResponse{})
+}
+
+// 取消订单
+func (b *bitmex) CancelOrder(a *Account, params map[string]interface{}) error {
+	return b.do(a, "DELETE", "/order", params, nil)
+}
+
+// 设置杠杆率
+func (b *bitmex) SetLeverage(a *Account, symbol string, leverage float64) error {
+	return b.do(a, "POST", "/position/leverage", map[string]interface{}{"symbol": symbol, "leverage": leverage}, nil)
+}
+
+// 未成交订单
+func (b *bitmex) Orders(a *Account) (orders []Order, err error) {
+	err = b.do(a, "GET", "/order", map[string]interface{}{"filter": `{"open":true}`, "count": 500}, &orders)
+	for i, o := range orders {
+		orders[i] = bitmexOrder(o)
+	}
+	return
+}
+
+// 持仓
+func (b *bitmex) Positions(a *Account) (positions []Position, err error) {
+	err = b.do(a, "GET", "/position", nil, &positions)
+	return
+}
+
+// bitmexParams order params in BitMEX format, flags become execInst
+func bitmexParams(params map[string]interface{}) map[string]interface{} {
+	p := make(map[string]interface{}, len(params))
+	for k, v := range params {
+		p[k] = v
+	}
+	if flags, ok := p["flags"].([]string); ok {
+		delete(p, "flags")
+		var inst []string
+		for _, v := range flags {
+			if e, ok := bitmexExecInst[v]; ok {
+				inst = append(inst, e)
+			}
+		}
+		if len(inst) > 0 {
+			p["execInst"] = strings.Join(inst, ",")
+		}
+	}
+	if t, ok := bitmexOrdType[fmt.Sprint(p["ordType"])]; ok {
+		p["ordType"] = t
+	}
+	return p
+}
+
+// bitmexOrder set neutral fields of order received from BitMEX
+func bitmexOrder(o Order) Order {
+	o.Flags = nil
+	for _, v := range strings.Split(o.ExecInst, ",") {
+		for flag, e := range bitmexExecInst {
+			if v == e {
+				o.Flags = append(o.Flags, flag)
+			}
+		}
+	}
+	o.Crossed = o.OrdStatus == "Canceled" && strings.Contains(o.Text, "ParticipateDoNotInitiate")
+	return o
+}
+
+// topics configured topics plus the tables required by trading, without duplicates
+func topics(symbols []string) (topics []string) {
+	seen := make(map[string]bool)
+	add := func(topic string) {
+		if topic == "" || seen[topic] {
+			return
+		}
+		seen[topic] = true
+		topics = append(topics, topic)
+	}
+
+	for _, v := range Conf.Subscribe.Topic {
+		add(strings.TrimSpace(v))
+	}
+	add("margin")
+	add("wallet")
+	for _, s := range symbols {
+		add("orderBook10:" + s)
+		add("instrument:" + s)
+		add("funding:" + s)
+	}
+	// 按成交价波动率调整价差 或策略使用成交强度时需要成交数据
+	for _, a := range accounts {
+		if !a.usesTrades() {
+			continue
+		}
+		for _, s := range a.trading().Symbol {
+			if s = strings.TrimSpace(s); s != "" {
+				add("trade:" + s)
+			}
+		}
+	}
+	if Conf.Quoting.Fair == "index" && Conf.Quoting.Index != "" {
+		// 指数没有盘口 只订阅 instrument
+		add("instrument:" + Conf.Quoting.Index)
+	}
+	return
+}
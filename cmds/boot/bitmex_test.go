@@ -0,0 +1,64 @@
+package boot
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBitmexOrderFlags(t *testing.T) {
+	params := map[string]interface{}{"symbol": "XBTUSD", "orderQty": 100.0, "stopPx": 6490.0, "ordType": OrdStopLimit, "flags": []string{FlagLastPrice, FlagReduceOnly}}
+	p := bitmexParams(params)
+	assert.Equal(t, "LastPrice,ReduceOnly", p["execInst"])
+	assert.Equal(t, "StopLimit", p["ordType"])
+	assert.Nil(t, p["flags"])
+	// 不改动原参数
+	assert.Equal(t, OrdStopLimit, params["ordType"])
+
+	p = bitmexParams(map[string]interface{}{"symbol": "XBTUSD", "flags": []string{}})
+	assert.Nil(t, p["execInst"])
+	assert.Nil(t, p["ordType"])
+
+	o := bitmexOrder(Order{OrdStatus: "Canceled", ExecInst: "ParticipateDoNotInitiate", Text: "Canceled: Order had execInst of ParticipateDoNotInitiate"})
+	assert.Equal(t, []string{FlagPostOnly}, o.Flags)
+	assert.True(t, isPostOnlyCanceled(o))
+	o = bitmexOrder(Order{OrdStatus: "Canceled", ExecInst: "ParticipateDoNotInitiate", Text: "Canceled via API"})
+	assert.False(t, isPostOnlyCanceled(o))
+	o = bitmexOrder(Order{OrdStatus: "New", ExecInst: "Close,LastPrice"})
+	assert.Equal(t, []string{FlagClose, FlagLastPrice}, o.Flags)
+	assert.True(t, isExit("", o.Flags))
+}
+
+func TestSign(t *testing.T) {
+	b := &bitmex{}
+	sign := b.sign("chNOOS4KvNXR_Xq4k4c9qsfoKWvnDecLATCRlcBwyKDYnWgO", "GET", "/api/v1/instrument", 1518064236, "")
+	assert.Equal(t, "c7682d435d0cfe87c16098df34ef2eb5a549d4c5a3c2b1f0f77b8af73423bf00", sign)
+}
+
+func TestBitmexRoute(t *testing.T) {
+	saved := accounts
+	defer func() {
+		accounts = saved
+		orderBook10 = make(map[string]OrderBook10)
+		spareBooks = make(map[string]OrderBook10)
+	}()
+	bm := newBitmex().(*bitmex)
+	a := newAccount(&AccountConfig{Name: DefaultAccount})
+	accounts = []*Account{a}
+
+	// 认证和订阅确认前 pong 不触发定时任务
+	bm.session.Expect("", []string{"order"}, false)
+	assert.Nil(t, bm.dispatch([]byte("pong")))
+
+	assert.Nil(t, bm.dispatch([]byte(`{"table":"orderBook10","action":"partial","data":[{"symbol":"XBTUSD","bids":[[6500,100]],"asks":[[6501,100]],"timestamp":"2018-10-01T00:00:00.000Z"}]}`)))
+	assert.Equal(t, 6501.0, orderBook10["XBTUSD"].Asks[0][0])
+
+	// 订单的 execInst 转换为订单标记 穿价被撤的被动单按盘口重新下单
+	clOrdID := newClOrdID(PurposeUnwind)
+	assert.Nil(t, bm.dispatch([]byte(`{"table":"order","action":"partial","keys":["orderID"],"data":[{"orderID":"1","clOrdID":"`+clOrdID+`","symbol":"XBTUSD","side":"Sell","price":6500,"orderQty":100,"execInst":"ParticipateDoNotInitiate,ReduceOnly","ordStatus":"New"}]}`)))
+	assert.Equal(t, []string{FlagPostOnly, FlagReduceOnly}, a.order[0].Flags)
+	assert.Nil(t, bm.dispatch([]byte(`{"table":"order","action":"update","data":[{"orderID":"1","ordStatus":"Canceled","text":"Canceled: Order had execInst of ParticipateDoNotInitiate","timestamp":"2018-10-01T00:00:01.000Z"}]}`)))
+	assert.Empty(t, a.order)
+	ops := drain(a)
+	assert.Len(t, ops, 1)
+	assert.Equal(t, 6501.0, ops[0].Params["price"])
+}
@@ -1,8 +1,10 @@
 package boot
 
 import (
+	"fmt"
 	"gopkg.in/ini.v1"
 	"gopkg.in/urfave/cli.v1"
+	"strings"
 )
 
 var (
@@ -18,6 +20,21 @@ type (
 		*AuthConfig
 		*Subscribe
 		*Trading
+		*Throttle
+		*Quoting
+		*ExecInst
+		*Exit
+		*State
+		*JournalConfig
+		*Risk
+		*FundingPolicy
+		*Signal
+		*MarginConfig
+		*ExchangeConfig
+		*Hedge
+		*Basis
+
+		accounts []*AccountConfig
 	}
 
 	WSConfig struct {
@@ -34,23 +51,154 @@ type (
 	}
 
 	AuthConfig struct {
-		Key    string
-		Secret string
+		Key      string
+		Secret   string
+		Accounts []string // 多账户 每个账户从 [Account.名称] 段读取
+	}
+
+	// 命名账户 段内可覆盖 Trading 和 Risk 的同名字段 未设置的沿用全局配置
+	AccountConfig struct {
+		Name    string `ini:"-"`
+		Key     string
+		Secret  string
+		Trading *Trading `ini:"-"`
+		Risk    *Risk    `ini:"-"`
 	}
 
 	Subscribe struct {
 		Topic []string
 	}
 
+	// SpreadMode: fixed / mid / trade 见 spread.go
 	Trading struct {
-		UnitQty    float64
-		MaxHoldQty float64
-		Symbol     []string
-		Spread     float64
-		PriceUint  float64
-		Range      int64
-		Leverage   float64
-		Watch      int64
+		UnitQty      float64
+		MaxHoldQty   float64
+		Symbol       []string
+		Spread       float64
+		PriceUint    float64
+		Range        int64
+		Leverage     float64
+		Watch        int64
+		SpreadMode   string
+		SpreadFactor float64
+		SpreadWindow int64
+		MinSpread    float64
+		MaxSpread    float64
+	}
+
+	// 0 表示不限制 Order* Message* 按账户和品种计数 Global* 为进程内所有账户的总数
+	Throttle struct {
+		OrderPerSecond   int64
+		OrderPerMinute   int64
+		MessagePerSecond int64
+		MessagePerMinute int64
+		GlobalPerSecond  int64
+		GlobalPerMinute  int64
+		Pause            int64
+	}
+
+	// Mode: top 盘口报价 / skew 按持仓偏移报价 / avellaneda 最优做市 / ladder 多档报价
+	// Progression: flat / linear / geometric
+	// Fair: book / mid / microprice / mark / index 见 fair.go
+	Quoting struct {
+		Mode         string
+		PriceSkew    float64
+		SizeSkew     float64
+		RiskAversion float64
+		Horizon      int64
+		Window       int64
+		Levels       int64
+		Offset       int64
+		Step         int64
+		Progression  string
+		SizeStep     float64
+		Fair         string
+		Index        string
+	}
+
+	// 按订单用途设置订单标记 多个用逗号分隔 postOnly reduceOnly close lastPrice markPrice indexPrice 由交易所适配转换
+	ExecInst struct {
+		Quote      string
+		Unwind     string
+		TakeProfit string
+		StopLoss   string
+		Hedge      string
+	}
+
+	// StopType: none 不止损 / stop 交易所止损市价单 / stoplimit 交易所止损限价单 / trailing 本地移动止损
+	Exit struct {
+		StopType        string
+		StopLoss        int64
+		StopLimitOffset int64
+	}
+
+	// 本地状态目录 DedupSize 为保留的已处理事件数
+	State struct {
+		Dir       string
+		DedupSize int64
+	}
+
+	// 成交和订单记录 sqlite 文件路径 留空不记录
+	JournalConfig struct {
+		Path string
+	}
+
+	// MaxLoss 本次运行允许的最大净亏损(XBT) 0 为不限制
+	Risk struct {
+		MaxLoss float64
+	}
+
+	// 资金费结算前 Window 分钟内 费率绝对值不低于 Threshold 时
+	// 停止在支付方向报价 Policy: none 不处理 / reduce 减仓至 Keep / flatten 平仓
+	FundingPolicy struct {
+		Policy    string
+		Window    int64
+		Threshold float64
+		Keep      float64
+	}
+
+	// 行情指标 LargeLiquidation 达到此张数的强平单算大额强平 记录并报警
+	Signal struct {
+		LargeLiquidation float64
+	}
+
+	MarginConfig struct {
+		Currency string
+		MaxUsage float64
+	}
+
+	// Name: bitmex / mock 内存模拟 不连接交易所
+	ExchangeConfig struct {
+		Name string
+	}
+
+	// 在 Symbol 上对冲做市持仓 Symbol 为空不对冲 Account 为空时用第一个账户
+	// Ratio 每张做市合约对冲的张数 敞口不小于 Threshold 时对冲 MaxQty 单笔上限 0 为不限
+	// Slippage 对冲限价单越过对手价的价格单位数 Timeout 秒内未成交撤单重下
+	// 设置 MaxUnhedged 时先以对手价对冲 敞口持续 MaxUnhedged 秒后才越过 Slippage 0 为始终越过
+	Hedge struct {
+		Symbol      string
+		Account     string
+		Ratio       float64
+		Threshold   float64
+		MaxQty      float64
+		Slippage    int64
+		Timeout     int64
+		MaxUnhedged int64
+	}
+
+	// 跨期价差做市 Quoting.Mode = basis 时在 Quote 合约上按 Hedge 合约中间价 + 公允基差 ± Spread 个价格单位报价
+	// 报价腿成交后在 Hedge 合约上反向补腿 Window 公允基差均值窗口秒数 MaxLegQty 单腿敞口上限
+	// 单腿敞口持续 MaxUnhedged 秒停止报价并越过 Slippage 补腿 Slippage Timeout 同 Hedge
+	Basis struct {
+		Quote       string
+		Hedge       string
+		Window      int64
+		Spread      float64
+		MaxLegQty   float64
+		MaxUnhedged int64
+		Slippage    int64
+		Timeout     int64
 	}
 )
 
@@ -78,6 +226,7 @@ func Default() *Config {
 		&AuthConfig{
 			"",
 			"",
+			nil,
 		},
 		&Subscribe{
 			[]string{},
@@ -91,14 +240,136 @@ func Default() *Config {
 			5,
 			10,
 			30,
+			"fixed",
+			1,
+			60,
+			1,
+			20,
+		},
+		&Throttle{
+			5,
+			30,
+			10,
+			40,
+			10,
+			60,
+			60,
+		},
+		&Quoting{
+			"top",
+			2,
+			0.5,
+			0.1,
+			60,
+			300,
+			3,
+			0,
+			1,
+			"flat",
+			0.5,
+			"book",
+			".BXBT",
+		},
+		&ExecInst{
+			"postOnly",
+			"reduceOnly",
+			"reduceOnly",
+			"lastPrice,reduceOnly",
+			"",
+		},
+		&Exit{
+			"none",
+			20,
+			2,
+		},
+		&State{
+			"state",
+			10000,
+		},
+		&JournalConfig{
+			"marketboy.db",
+		},
+		&Risk{
+			0,
+		},
+		&FundingPolicy{
+			"none",
+			30,
+			0.0005,
+			0,
+		},
+		&Signal{
+			100000,
+		},
+		&MarginConfig{
+			"XBt",
+			0.9,
 		},
+		&ExchangeConfig{
+			"bitmex",
+		},
+		&Hedge{
+			"",
+			"",
+			1,
+			100,
+			0,
+			2,
+			10,
+			0,
+		},
+		&Basis{
+			"",
+			"",
+			300,
+			5,
+			500,
+			30,
+			2,
+			10,
+		},
+		nil,
 	}
 
 }
 
 // LoadFromIni load config from ini override default config
 func (config *Config) LoadFromIni() (err error) {
-	return ini.MapTo(config, config.ConfigFile)
+	cfg, err := ini.Load(config.ConfigFile)
+	if err != nil {
+		return
+	}
+	if err = cfg.MapTo(config); err != nil {
+		return
+	}
+
+	config.accounts = nil
+	for _, name := range config.AuthConfig.Accounts {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		section := "Account." + name
+		if !cfg.HasSection(section) {
+			return fmt.Errorf("account %s: section [%s] not found", name, section)
+		}
+		trading, risk := *config.Trading, *config.Risk
+		a := &AccountConfig{Name: name, Trading: &trading, Risk: &risk}
+		for _, v := range []interface{}{a, a.Trading, a.Risk} {
+			if err = cfg.Section(section).MapTo(v); err != nil {
+				return
+			}
+		}
+		config.accounts = append(config.accounts, a)
+	}
+	return
+}
+
+// AccountConfigs configured accounts, the single AuthConfig key as account default if none
+func (config *Config) AccountConfigs() []*AccountConfig {
+	if len(config.accounts) > 0 {
+		return config.accounts
+	}
+	return []*AccountConfig{{DefaultAccount, config.AuthConfig.Key, config.AuthConfig.Secret, config.Trading, config.Risk}}
 }
 
 // Load load config from command line param
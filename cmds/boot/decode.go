@@ -0,0 +1,411 @@
+package boot
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/tidwall/gjson"
+	"strconv"
+	"sync"
+)
+
+// orderBook10 每秒数百条 不走 encoding/json 和 reflect
+// 手写扫描器解析到池化的缓冲区 档位数值存放在每个盘口自带的连续数组中 稳定后不再按档位分配内存
+
+var (
+	tablePrefix = []byte(`{"table":"`)
+
+	bookDecoders = sync.Pool{
+		New: func() interface{} { return &bookDecoder{} },
+	}
+
+	// 10^0 ~ 10^22 在 float64 中可精确表示
+	pow10 = [...]float64{1e0, 1e1, 1e2, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8, 1e9, 1e10,
+		1e11, 1e12, 1e13, 1e14, 1e15, 1e16, 1e17, 1e18, 1e19, 1e20, 1e21, 1e22}
+)
+
+type (
+	scanner struct {
+		b []byte
+		i int
+	}
+
+	// 解析 orderBook10 消息的临时缓冲
+	bookDecoder struct {
+		scanner
+		msg    OrderBook10Msg
+		levels []float64
+		bids   []int // 每档在 levels 中的结束位置
+		asks   []int
+		names  map[string]string // 复用 symbol 和 action 字符串
+	}
+)
+
+// tableOf table name of frame without allocating, BitMEX puts table first
+func tableOf(msg []byte) []byte {
+	if bytes.HasPrefix(msg, tablePrefix) {
+		rest := msg[len(tablePrefix):]
+		if end := bytes.IndexByte(rest, '"'); end >= 0 {
+			return rest[:end]
+		}
+	}
+	t := gjson.GetBytes(msg, "table")
+	if !t.Exists() {
+		return nil
+	}
+	return []byte(t.String())
+}
+
+func (s *scanner) reset(b []byte) {
+	s.b, s.i = b, 0
+}
+
+func (s *scanner) err(expect string) error {
+	return fmt.Errorf("invalid json at %d: expect %s", s.i, expect)
+}
+
+func (s *scanner) skipSpace() {
+	for s.i < len(s.b) {
+		switch s.b[s.i] {
+		case ' ', '\t', '\n', '\r':
+			s.i++
+		default:
+			return
+		}
+	}
+}
+
+// next skip spaces and return next byte, 0 at end
+func (s *scanner) next() byte {
+	s.skipSpace()
+	if s.i >= len(s.b) {
+		return 0
+	}
+	return s.b[s.i]
+}
+
+func (s *scanner) expect(c byte) error {
+	if s.next() != c {
+		return s.err(string(c))
+	}
+	s.i++
+	return nil
+}
+
+// more consume ',' and report true, or consume closing c and report false
+func (s *scanner) more(c byte) (bool, error) {
+	switch s.next() {
+	case ',':
+		s.i++
+		return true, nil
+	case c:
+		s.i++
+		return false, nil
+	}
+	return false, s.err(", or " + string(c))
+}
+
+// str raw bytes of string, escapes are kept as is
+func (s *scanner) str() ([]byte, error) {
+	if err := s.expect('"'); err != nil {
+		return nil, err
+	}
+	start := s.i
+	for s.i < len(s.b) {
+		switch s.b[s.i] {
+		case '\\':
+			s.i += 2
+			continue
+		case '"':
+			s.i++
+			return s.b[start : s.i-1], nil
+		}
+		s.i++
+	}
+	return nil, s.err(`"`)
+}
+
+func (s *scanner) num() (float64, error) {
+	s.skipSpace()
+	start := s.i
+	neg := false
+	if s.i < len(s.b) && s.b[s.i] == '-' {
+		neg = true
+		s.i++
+	}
+
+	// 常见的十进制小数 尾数不超过 2^53 时一次除法即可正确舍入
+	var mant uint64
+	digits, frac := 0, -1
+	exact := true
+	for ; s.i < len(s.b); s.i++ {
+		c := s.b[s.i]
+		switch {
+		case c >= '0' && c <= '9':
+			mant = mant*10 + uint64(c-'0')
+			digits++
+			if frac >= 0 {
+				frac++
+			}
+		case c == '.' && frac < 0:
+			frac = 0
+		case c == 'e' || c == 'E' || c == '+' || (c == '-' && s.i > start):
+			exact = false
+		default:
+			goto done
+		}
+	}
+done:
+	if digits == 0 {
+		return 0, s.err("number")
+	}
+	if exact && digits <= 15 && frac < len(pow10) {
+		f := float64(mant)
+		if frac > 0 {
+			f /= pow10[frac]
+		}
+		if neg {
+			f = -f
+		}
+		return f, nil
+	}
+	return strconv.ParseFloat(string(s.b[start:s.i]), 64)
+}
+
+// skip skip any value
+func (s *scanner) skip() error {
+	switch c := s.next(); c {
+	case '"':
+		_, err := s.str()
+		return err
+	case '{', '[':
+		end := byte('}')
+		if c == '[' {
+			end = ']'
+		}
+		s.i++
+		if s.next() == end {
+			s.i++
+			return nil
+		}
+		for {
+			if c == '{' {
+				if _, err := s.str(); err != nil {
+					return err
+				}
+				if err := s.expect(':'); err != nil {
+					return err
+				}
+			}
+			if err := s.skip(); err != nil {
+				return err
+			}
+			more, err := s.more(end)
+			if err != nil || !more {
+				return err
+			}
+		}
+	case 't', 'f', 'n':
+		for s.i < len(s.b) && s.b[s.i] >= 'a' && s.b[s.i] <= 'z' {
+			s.i++
+		}
+		return nil
+	default:
+		_, err := s.num()
+		return err
+	}
+}
+
+// object call field for each key of object
+func (s *scanner) object(field func(key []byte) error) error {
+	if err := s.expect('{'); err != nil {
+		return err
+	}
+	if s.next() == '}' {
+		s.i++
+		return nil
+	}
+	for {
+		key, err := s.str()
+		if err != nil {
+			return err
+		}
+		if err = s.expect(':'); err != nil {
+			return err
+		}
+		if err = field(key); err != nil {
+			return err
+		}
+		more, err := s.more('}')
+		if err != nil || !more {
+			return err
+		}
+	}
+}
+
+// array call elem for each element of array
+func (s *scanner) array(elem func() error) error {
+	if err := s.expect('['); err != nil {
+		return err
+	}
+	if s.next() == ']' {
+		s.i++
+		return nil
+	}
+	for {
+		if err := elem(); err != nil {
+			return err
+		}
+		more, err := s.more(']')
+		if err != nil || !more {
+			return err
+		}
+	}
+}
+
+// name interned string of b
+func (d *bookDecoder) name(b []byte) string {
+	if d.names == nil {
+		d.names = make(map[string]string)
+	}
+	if v, ok := d.names[string(b)]; ok {
+		return v
+	}
+	v := string(b)
+	d.names[v] = v
+	return v
+}
+
+// side parse levels of one side, returns end offset of each level in d.levels
+func (d *bookDecoder) side(ends []int) ([]int, error) {
+	ends = ends[:0]
+	err := d.array(func() error {
+		err := d.array(func() error {
+			v, err := d.num()
+			d.levels = append(d.levels, v)
+			return err
+		})
+		ends = append(ends, len(d.levels))
+		return err
+	})
+	return ends, err
+}
+
+// book parse one book into dst, reusing its buffers
+func (d *bookDecoder) book(dst *OrderBook10) error {
+	d.levels = d.levels[:0]
+	var bidsStart, asksStart int
+	err := d.object(func(key []byte) (err error) {
+		switch string(key) {
+		case "symbol":
+			var v []byte
+			if v, err = d.str(); err == nil {
+				dst.Symbol = d.name(v)
+			}
+		case "timestamp":
+			var v []byte
+			if v, err = d.str(); err == nil && string(v) != dst.Timestamp {
+				dst.Timestamp = string(v)
+			}
+		case "bids":
+			bidsStart = len(d.levels)
+			d.bids, err = d.side(d.bids)
+		case "asks":
+			asksStart = len(d.levels)
+			d.asks, err = d.side(d.asks)
+		default:
+			err = d.skip()
+		}
+		return
+	})
+	if err != nil {
+		return err
+	}
+	dst.setLevels(d.levels, bidsStart, d.bids, asksStart, d.asks)
+	return nil
+}
+
+// setLevels copy levels into own buffer and slice bids and asks from it
+func (b *OrderBook10) setLevels(levels []float64, bidsStart int, bids []int, asksStart int, asks []int) {
+	b.levels = append(b.levels[:0], levels...)
+	b.Bids = b.Bids[:0]
+	start := bidsStart
+	for _, end := range bids {
+		b.Bids = append(b.Bids, b.levels[start:end:end])
+		start = end
+	}
+	b.Asks = b.Asks[:0]
+	start = asksStart
+	for _, end := range asks {
+		b.Asks = append(b.Asks, b.levels[start:end:end])
+		start = end
+	}
+}
+
+// CopyFrom copy book into b, reusing b's buffers
+func (b *OrderBook10) CopyFrom(src OrderBook10) {
+	b.Symbol, b.Timestamp = src.Symbol, src.Timestamp
+	b.levels = b.levels[:0]
+	b.Bids, b.Asks = b.Bids[:0], b.Asks[:0]
+	for _, v := range src.Bids {
+		b.levels = append(b.levels, v...)
+	}
+	for _, v := range src.Asks {
+		b.levels = append(b.levels, v...)
+	}
+	start := 0
+	for _, v := range src.Bids {
+		b.Bids = append(b.Bids, b.levels[start:start+len(v):start+len(v)])
+		start += len(v)
+	}
+	for _, v := range src.Asks {
+		b.Asks = append(b.Asks, b.levels[start:start+len(v):start+len(v)])
+		start += len(v)
+	}
+}
+
+// decode parse orderBook10 frame, the returned message is owned by the decoder
+func (d *bookDecoder) decode(msg []byte) (*OrderBook10Msg, error) {
+	d.reset(msg)
+	m := &d.msg
+	m.Data = m.Data[:0]
+	err := d.object(func(key []byte) (err error) {
+		switch string(key) {
+		case "table", "action":
+			var v []byte
+			if v, err = d.str(); err != nil {
+				return
+			}
+			if string(key) == "table" {
+				m.Table = d.name(v)
+			} else {
+				m.Action = d.name(v)
+			}
+		case "data":
+			err = d.array(func() error {
+				if len(m.Data) < cap(m.Data) {
+					m.Data = m.Data[:len(m.Data)+1]
+				} else {
+					m.Data = append(m.Data, OrderBook10{})
+				}
+				return d.book(&m.Data[len(m.Data)-1])
+			})
+		default:
+			err = d.skip()
+		}
+		return
+	})
+	return m, err
+}
+
+// decodeOrderBook10 parse frame with a pooled decoder and pass it to fn,
+// books must be copied with CopyFrom to be kept after fn returns
+func decodeOrderBook10(msg []byte, fn func(*OrderBook10Msg)) error {
+	d := bookDecoders.Get().(*bookDecoder)
+	defer bookDecoders.Put(d)
+	m, err := d.decode(msg)
+	if err != nil {
+		return err
+	}
+	fn(m)
+	return nil
+}
@@ -0,0 +1,119 @@
+package boot
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"testing"
+)
+
+// syntheticFrames generated orderBook10 frames in the BitMEX wire format, not a capture of live traffic
+func syntheticFrames(tb testing.TB) (frames [][]byte) {
+	b, err := ioutil.ReadFile("testdata/orderBook10_synthetic.jsonl")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		frames = append(frames, append([]byte(nil), scanner.Bytes()...))
+	}
+	return
+}
+
+func TestDecodeOrderBook10(t *testing.T) {
+	for _, frame := range syntheticFrames(t) {
+		expected := &OrderBook10Msg{}
+		assert.Nil(t, json.Unmarshal(frame, expected))
+		assert.Nil(t, decodeOrderBook10(frame, func(m *OrderBook10Msg) {
+			assert.Equal(t, expected.Table, m.Table)
+			assert.Equal(t, expected.Action, m.Action)
+			assert.Len(t, m.Data, len(expected.Data))
+			for i, book := range m.Data {
+				assert.Equal(t, expected.Data[i].Symbol, book.Symbol)
+				assert.Equal(t, expected.Data[i].Timestamp, book.Timestamp)
+				assert.Equal(t, expected.Data[i].Bids, book.Bids)
+				assert.Equal(t, expected.Data[i].Asks, book.Asks)
+			}
+		}))
+	}
+
+	// exponents, negatives, escapes and unknown fields
+	frame := []byte(` {"table" : "orderBook10", "extra":{"a":[1,true,null,"x\"y"]}, "data":[{"asks":[[1e-05, -2.5]], "symbol":"XBTUSD","bids":[], "x":false}], "action":"update"}`)
+	assert.Nil(t, decodeOrderBook10(frame, func(m *OrderBook10Msg) {
+		assert.Equal(t, "update", m.Action)
+		assert.Equal(t, "XBTUSD", m.Data[0].Symbol)
+		assert.Equal(t, []Ask{{1e-05, -2.5}}, m.Data[0].Asks)
+		assert.Empty(t, m.Data[0].Bids)
+	}))
+	assert.NotNil(t, decodeOrderBook10([]byte(`{"table":"orderBook10","data":[{"bids":[[1,]]}]}`), func(*OrderBook10Msg) {}))
+
+	assert.Equal(t, "orderBook10", string(tableOf(frame)))
+	assert.Nil(t, tableOf([]byte(`{"success":true}`)))
+
+	// copied book keeps its values when the decoder is reused
+	var kept OrderBook10
+	frames := syntheticFrames(t)
+	decodeOrderBook10(frames[0], func(m *OrderBook10Msg) { kept.CopyFrom(m.Data[0]) })
+	first := append([]float64(nil), kept.Bids[0]...)
+	decodeOrderBook10(frames[1], func(m *OrderBook10Msg) {})
+	assert.Equal(t, first, []float64(kept.Bids[0]))
+
+	// 已取出的盘口在下一次更新时不被改写
+	defer func() {
+		orderBook10 = make(map[string]OrderBook10)
+		spareBooks = make(map[string]OrderBook10)
+	}()
+	onBook(OrderBook10{Symbol: "XBTUSD", Bids: []Bid{{6500, 1}}, Asks: []Ask{{6501, 1}}})
+	book := orderBook10["XBTUSD"]
+	onBook(OrderBook10{Symbol: "XBTUSD", Bids: []Bid{{6600, 1}}, Asks: []Ask{{6601, 1}}})
+	assert.Equal(t, 6500.0, book.Bids[0][0])
+	assert.Equal(t, 6600.0, orderBook10["XBTUSD"].Bids[0][0])
+}
+
+func BenchmarkDecodeOrderBook10(b *testing.B) {
+	frames := syntheticFrames(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		decodeOrderBook10(frames[i%len(frames)], func(*OrderBook10Msg) {})
+	}
+}
+
+func BenchmarkDecodeOrderBook10Json(b *testing.B) {
+	frames := syntheticFrames(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		obm := &OrderBook10Msg{}
+		json.Unmarshal(frames[i%len(frames)], obm)
+	}
+}
+
+func BenchmarkDispatchOrderBook10(b *testing.B) {
+	bm := newBitmex().(*bitmex)
+	defer log.SetLevel(log.GetLevel())
+	log.SetLevel(log.WarnLevel)
+	frames := syntheticFrames(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bm.dispatch(frames[i%len(frames)])
+	}
+}
+
+func BenchmarkHandleOrder(b *testing.B) {
+	bm := newBitmex().(*bitmex)
+	defer log.SetLevel(log.GetLevel())
+	log.SetLevel(log.WarnLevel)
+	a := newAccount(&AccountConfig{Name: "bench"})
+	bm.handleOrder(a, tableMsg(`{"table":"order","action":"partial","keys":["orderID"],"data":[{"orderID":"a","symbol":"XBTUSD","side":"Buy","price":6500,"leavesQty":100,"ordStatus":"New"},{"orderID":"b","symbol":"XBTUSD","side":"Sell","price":6501,"leavesQty":100,"ordStatus":"New"}]}`))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bm.handleOrder(a, tableMsg(fmt.Sprintf(`{"table":"order","action":"update","data":[{"orderID":"a","price":%d,"timestamp":"2018-10-01T00:00:00.000Z"}]}`, 6000+i%500)))
+	}
+}
@@ -0,0 +1,147 @@
+package boot
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+type (
+	// 已处理事件 按先进先出保留最近 size 个 持久化到 file
+	dedup struct {
+		sync.Mutex
+		file       string
+		size       int
+		ids        map[string]bool
+		queue      []string
+		dirty      bool
+		duplicates int64
+	}
+)
+
+func newDedup(file string, size int) *dedup {
+	return &dedup{
+		file: file,
+		size: size,
+		ids:  make(map[string]bool),
+	}
+}
+
+// loadState load processed events of account
+func (a *Account) loadState() (err error) {
+	dir := a.stateDir()
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	size := int(Conf.State.DedupSize)
+	a.executions = newDedup(filepath.Join(dir, "executions.json"), size)
+	a.orderEvents = newDedup(filepath.Join(dir, "orders.json"), size)
+	if err = a.executions.load(); err != nil {
+		return
+	}
+	return a.orderEvents.load()
+}
+
+// saveState persist processed events of account
+func (a *Account) saveState() (err error) {
+	if err = a.executions.save(); err != nil {
+		return
+	}
+	return a.orderEvents.save()
+}
+
+// Duplicates number of duplicated executions and order events seen by all accounts
+func Duplicates() (n int64) {
+	for _, a := range accounts {
+		n += a.executions.Duplicates() + a.orderEvents.Duplicates()
+	}
+	return
+}
+
+// orderMarker version marker of an order event, hash of the row as pushed
+func orderMarker(orderID string, raw json.RawMessage) string {
+	h := sha1.Sum(raw)
+	return orderID + ":" + hex.EncodeToString(h[:8])
+}
+
+func (d *dedup) add(id string) {
+	d.ids[id] = true
+	d.queue = append(d.queue, id)
+	for len(d.queue) > d.size {
+		delete(d.ids, d.queue[0])
+		d.queue = d.queue[1:]
+	}
+	d.dirty = true
+}
+
+// seen report whether id was processed before and mark it as processed
+func (d *dedup) seen(id string) bool {
+	d.Lock()
+	defer d.Unlock()
+	if d.ids[id] {
+		d.duplicates++
+		return true
+	}
+	d.add(id)
+	return false
+}
+
+// mark mark id as processed without counting it as duplicate
+func (d *dedup) mark(id string) {
+	d.Lock()
+	defer d.Unlock()
+	if !d.ids[id] {
+		d.add(id)
+	}
+}
+
+func (d *dedup) Duplicates() int64 {
+	d.Lock()
+	defer d.Unlock()
+	return d.duplicates
+}
+
+func (d *dedup) load() (err error) {
+	d.Lock()
+	defer d.Unlock()
+	b, err := ioutil.ReadFile(d.file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return
+	}
+	var ids []string
+	if err = json.Unmarshal(b, &ids); err != nil {
+		return
+	}
+	for _, id := range ids {
+		if !d.ids[id] {
+			d.add(id)
+		}
+	}
+	d.dirty = false
+	return
+}
+
+// save write to a temp file then rename, so a crash never leaves a truncated file
+func (d *dedup) save() (err error) {
+	d.Lock()
+	defer d.Unlock()
+	if d.file == "" || !d.dirty {
+		return
+	}
+	tmp := d.file + ".tmp"
+	if err = ioutil.WriteFile(tmp, mustMarshal(d.queue), 0644); err != nil {
+		return
+	}
+	if err = os.Rename(tmp, d.file); err != nil {
+		return
+	}
+	d.dirty = false
+	return
+}
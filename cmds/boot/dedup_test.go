@@ -0,0 +1,35 @@
+package boot
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDedup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "marketboy")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "executions.json")
+
+	d := newDedup(file, 2)
+	d.mark("a")
+	assert.False(t, d.seen("b"))
+	assert.True(t, d.seen("a"))
+	assert.True(t, d.seen("b"))
+	assert.Equal(t, int64(2), d.Duplicates())
+
+	// oldest id is evicted once full
+	assert.False(t, d.seen("c"))
+	assert.False(t, d.seen("a"))
+	assert.Nil(t, d.save())
+
+	loaded := newDedup(file, 2)
+	assert.Nil(t, loaded.load())
+	assert.True(t, loaded.seen("c"))
+	assert.True(t, loaded.seen("a"))
+	assert.False(t, loaded.seen("b"))
+	assert.Equal(t, int64(2), loaded.Duplicates())
+}
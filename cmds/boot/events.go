@@ -0,0 +1,320 @@
+package boot
+
+import (
+	log "github.com/sirupsen/logrus"
+	"math"
+	"time"
+)
+
+// 交易所无关的事件处理 各交易所适配解码后调用 均在适配的事件循环中执行
+
+// onBook 盘口更新 复制到备用缓冲后替换本地存储 已取出的盘口不会被改写到一半
+func onBook(v OrderBook10) {
+	book := spareBooks[v.Symbol]
+	book.CopyFrom(v)
+	spareBooks[v.Symbol] = orderBook10[v.Symbol]
+	orderBook10[v.Symbol] = book
+	signalsFor(book.Symbol).OnBook(book, parseTime(book.Timestamp))
+	basis.OnBook(book, parseTime(book.Timestamp))
+	for _, a := range accounts {
+		a.exits.trail(book)
+	}
+	if !log.IsLevelEnabled(log.DebugLevel) || len(book.Asks) < 5 || len(book.Bids) < 5 || len(book.Asks) < int(Conf.Trading.Range) || len(book.Bids) < int(Conf.Trading.Range) {
+		return
+	}
+	log.Debug("---")
+	log.Debugf("range: %v ~ %v", book.Asks[Conf.Trading.Range-1][0], book.Bids[Conf.Trading.Range-1][0])
+	log.Debugf("Asks: %v\t%v\t%v\t%v\t%v", book.Asks[0], book.Asks[1], book.Asks[2], book.Asks[3], book.Asks[4])
+	log.Debugf("Bids: %v\t%v\t%v\t%v\t%v", book.Bids[0], book.Bids[1], book.Bids[2], book.Bids[3], book.Bids[4])
+	log.Debug("---")
+}
+
+// onTrade 公开成交
+func onTrade(v Trade) {
+	signalsFor(v.Symbol).OnTrade(v, parseTime(v.Timestamp))
+}
+
+// onQuote 最优报价
+func onQuote(v BookQuote) {
+	signalsFor(v.Symbol).OnQuote(v, parseTime(v.Timestamp))
+}
+
+// onLiquidation 新的强平单
+func onLiquidation(v Liquidation) {
+	if signalsFor(v.Symbol).OnLiquidation(v, time.Now()) {
+		alert("%s large liquidation %s %v at %v", v.Symbol, v.Side, v.LeavesQty, v.Price)
+	}
+}
+
+// onExecution execution of account, history ones are recorded only
+func (a *Account) onExecution(v Execution, history bool) {
+	if history {
+		a.executions.mark(v.ExecID)
+		journalExecution(a.Name, v)
+		return
+	}
+	if a.executions.seen(v.ExecID) {
+		log.Debugf("execution %s already processed", v.ExecID)
+		return
+	}
+	journalExecution(a.Name, v)
+	a.ledger.Apply(v)
+	hedger.seen(v.ClOrdID)
+	a.legs.seen(v.ClOrdID)
+	if v.ExecType != "Trade" || v.LastQty <= 0 {
+		return
+	}
+	log.Infof("%s order %s filled at %v, qty is %v, %v left", v.Side, v.OrderID, v.LastPx, v.LastQty, v.LeavesQty)
+	a.applyFill(v)
+	a.exits.onExecution(v)
+}
+
+// 按成交增量更新持仓 持仓推送已包含的成交不再计入
+func (a *Account) applyFill(e Execution) {
+	if a.position == nil {
+		a.position = make(map[string]Position)
+	}
+	qty := e.LastQty
+	if e.Side == "Sell" {
+		qty = -qty
+	}
+	if !a.fills.add(e, qty) {
+		return
+	}
+	p := a.position[e.Symbol]
+	p.Symbol = e.Symbol
+	p.CurrentQty += qty
+	a.position[e.Symbol] = p
+}
+
+// onOrder order of account changed from status, amended if update
+func (a *Account) onOrder(from string, o Order, update bool) {
+	journalOrder(a.Name, from, o)
+	hedger.seen(o.ClOrdID)
+	a.legs.seen(o.ClOrdID)
+	if update {
+		a.amends.ack(o)
+	}
+	if isPostOnlyCanceled(o) {
+		a.handlePostOnlyCanceled(o)
+	} else if !isWorking(o) {
+		delete(a.reposts, o.ClOrdID)
+	}
+}
+
+// setOrders replace open orders of account
+func (a *Account) setOrders(orders []Order) {
+	a.order = orders
+	log.Debug(len(a.order))
+}
+
+// setPosition replace position of symbol, fills it does not include yet are added
+func (a *Account) setPosition(p Position) {
+	if a.position == nil {
+		a.position = make(map[string]Position)
+	}
+	p.CurrentQty += a.fills.caught(p)
+	a.position[p.Symbol] = p
+}
+
+// onPing 定时任务 行情和账户数据就绪后由适配按 Watch 间隔调用
+func onPing() {
+	for _, a := range accounts {
+		a.ping()
+	}
+	hedge(time.Now())
+	logRisks()
+
+	// 已处理事件
+	for _, a := range accounts {
+		if err := a.saveState(); err != nil {
+			log.Error(err)
+		}
+	}
+	log.Infof("duplicated events: %d", Duplicates())
+}
+
+// ping 账户的定时任务
+func (a *Account) ping() {
+	a.checkHalt()
+	a.checkAccountRisk()
+	if reason := a.Halted(); reason != "" {
+		log.Infof("account %s halted: %s", a.Name, reason)
+		// 暂停时仍维护持仓的止损
+		a.exits.maintain(a.order, time.Now())
+		return
+	}
+	trading := a.trading()
+
+	// 移仓
+	for _, v := range a.order {
+
+		if v.OrdStatus != "New" {
+			continue
+		}
+
+		// 报价订单由 plan 改单调整 止盈止损单由 exits 维护 对冲单由 hedger 维护
+		switch purposeOf(v.ClOrdID) {
+		case PurposeQuote, PurposeTakeProfit, PurposeStopLoss, PurposeHedge:
+			continue
+		}
+
+		if v.Side == "Buy" && v.Price >= orderBook10[v.Symbol].Bids[trading.Range-1][0] {
+			continue
+		}
+
+		if v.Side == "Sell" && v.Price <= orderBook10[v.Symbol].Asks[trading.Range-1][0] {
+			continue
+		}
+		action := "cancel"
+		params := make(map[string]interface{})
+		params["orderID"] = v.OrderID
+		a.submit(Operate{
+			action,
+			v.Symbol,
+			params,
+		})
+		log.Infof("%s order %s to be canceled, price is %v, qty is %v", v.Side, v.OrderID, v.Price, v.OrderQty)
+	}
+
+	// 超出最大持仓量 对冲持仓由对冲维护 价差做市两条腿由 MaxLegQty 和补腿维护
+	for k, v := range a.position {
+		if hedgeSymbol(k) || a.basisLeg(k) {
+			continue
+		}
+
+		toBuy := true
+		toSell := true
+		for _, vv := range a.order {
+			if k != vv.Symbol {
+				continue
+			}
+			if vv.Side == "Buy" && vv.Price == orderBook10[vv.Symbol].Bids[0][0] && vv.OrdStatus == "New" {
+				log.Debug(vv)
+				toBuy = false
+				break
+			}
+		}
+
+		for _, vv := range a.order {
+			if k != vv.Symbol {
+				continue
+			}
+			if vv.Side == "Sell" && vv.Price == orderBook10[vv.Symbol].Asks[0][0] && vv.OrdStatus == "New" {
+				log.Debug(vv)
+				toSell = false
+				break
+			}
+		}
+
+		log.Infof("CurrentQty: %v", v.CurrentQty)
+		if a.limiter.isPaused(k, time.Now()) {
+			log.Infof("%s quoting paused by throttle", k)
+			continue
+		}
+		if math.Abs(v.CurrentQty) > trading.MaxHoldQty {
+			action := "create"
+			params := make(map[string]interface{})
+			params["symbol"] = k
+			params["orderQty"] = trading.UnitQty * 2
+			params["clOrdID"] = newClOrdID(PurposeUnwind)
+			params["side"] = "Buy"
+			params["price"] = orderBook10[k].Bids[0][0]
+			if v.CurrentQty > 0 {
+				params["side"] = "Sell"
+				params["price"] = orderBook10[k].Asks[0][0]
+			}
+			if (v.CurrentQty > 0 && toSell) || (v.CurrentQty < 0 && toBuy) {
+				a.submit(Operate{
+					action,
+					k,
+					params,
+				})
+				log.Infof("%s order to be created at %v, qty is %v", k, params["price"], params["orderQty"])
+			}
+		}
+	}
+
+	// 止盈止损
+	a.exits.maintain(a.order, time.Now())
+
+	// 资金费结算前减仓
+	for _, s := range trading.Symbol {
+		a.fundingUnwind(s, time.Now())
+	}
+
+	// 价差做市补腿
+	a.legIn(time.Now())
+
+	// 填价
+	for _, s := range trading.Symbol {
+		if len(orderBook10[s].Bids) == 0 || len(orderBook10[s].Asks) == 0 {
+			continue
+		}
+
+		if a.limiter.isPaused(s, time.Now()) {
+			log.Infof("%s quoting paused by throttle", s)
+			continue
+		}
+
+		if !a.checkRisk(s) || !a.checkLegs(s, time.Now()) {
+			continue
+		}
+
+		quotes := fundingQuotes(s, a.quoter().Quotes(a.snapshot(s)), time.Now())
+
+		// 超出最大持仓 只保留减仓方向的报价
+		if qty := a.position[s].CurrentQty; math.Abs(qty) > trading.MaxHoldQty {
+			log.Info("reach max hold Qty, stop create order")
+			var reduce []Quote
+			for _, q := range quotes {
+				if (qty > 0 && q.Side == "Sell") || (qty < 0 && q.Side == "Buy") {
+					reduce = append(reduce, q)
+				}
+			}
+			quotes = reduce
+		}
+		for _, op := range plan(s, quotes, a.amends.apply(workingQuotes(s, a.order), time.Now())) {
+			a.submit(op)
+			log.Infof("%s order to be %s: %v", s, op.Action, op.Params)
+		}
+	}
+}
+
+// 被动单因穿价被撤 报价单由 plan 重新报价 其他订单按盘口被动价重新下单 连续 MaxRepost 次被撤后放弃
+func (a *Account) handlePostOnlyCanceled(o Order) {
+	purpose := purposeOf(o.ClOrdID)
+	log.Infof("%s %s order %s canceled on cross at %v", o.Symbol, o.Side, o.OrderID, o.Price)
+	if purpose == "" || purpose == PurposeQuote {
+		return
+	}
+	n := a.reposts[o.ClOrdID] + 1
+	delete(a.reposts, o.ClOrdID)
+	if n > MaxRepost {
+		alert("%s %s order %s canceled on cross %d times, given up", o.Symbol, o.Side, o.ClOrdID, MaxRepost)
+		return
+	}
+	if clOrdID, ok := a.exits.onPostOnlyCanceled(o); ok {
+		if clOrdID != "" {
+			a.reposts[clOrdID] = n
+		}
+		return
+	}
+
+	book := orderBook10[o.Symbol]
+	if len(book.Bids) == 0 || len(book.Asks) == 0 {
+		return
+	}
+	q := Quote{o.Side, book.Bids[0][0], o.LeavesQty}
+	if o.Side == "Sell" {
+		q.Price = book.Asks[0][0]
+	}
+	if q.Qty <= 0 {
+		q.Qty = o.OrderQty - o.CumQty
+	}
+	op := createOp(o.Symbol, q, purpose)
+	if a.submit(op) {
+		a.reposts[op.Params["clOrdID"].(string)] = n
+		log.Infof("%s order to be created at %v, qty is %v", q.Side, q.Price, q.Qty)
+	}
+}
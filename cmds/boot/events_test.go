@@ -0,0 +1,76 @@
+package boot
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPostOnlyCanceled(t *testing.T) {
+	defer func() { orderBook10 = make(map[string]OrderBook10) }()
+	orderBook10["XBTUSD"] = OrderBook10{Symbol: "XBTUSD", Bids: []Bid{{6500, 100}}, Asks: []Ask{{6501, 100}}}
+	a := newAccount(&AccountConfig{Name: "test"})
+
+	crossed := Order{OrderID: "1", ClOrdID: newClOrdID(PurposeUnwind), Symbol: "XBTUSD", Side: "Sell", Price: 6500, OrderQty: 100, OrdStatus: "Canceled", Crossed: true}
+	assert.True(t, isPostOnlyCanceled(crossed))
+	assert.False(t, isPostOnlyCanceled(Order{OrdStatus: "Canceled", Text: "Canceled via API"}))
+
+	// 报价单由 plan 重新报价
+	quote := crossed
+	quote.ClOrdID = newClOrdID(PurposeQuote)
+	a.onOrder("New", quote, false)
+	assert.Empty(t, drain(a))
+
+	// 其他订单按被动价重新下单 连续被撤 MaxRepost 次后放弃
+	o := crossed
+	for i := 0; i < MaxRepost; i++ {
+		a.onOrder("New", o, false)
+		ops := drain(a)
+		assert.Len(t, ops, 1)
+		assert.Equal(t, 6501.0, ops[0].Params["price"])
+		assert.Equal(t, 100.0, ops[0].Params["orderQty"])
+		assert.Equal(t, PurposeUnwind, purposeOf(ops[0].Params["clOrdID"].(string)))
+		o.ClOrdID = ops[0].Params["clOrdID"].(string)
+	}
+	a.onOrder("New", o, false)
+	assert.Empty(t, drain(a))
+	assert.Empty(t, a.reposts)
+}
+
+func TestFills(t *testing.T) {
+	bm := newBitmex().(*bitmex)
+	a := newAccount(&AccountConfig{Name: "test"})
+	position := func(action, data string) {
+		assert.Nil(t, bm.handlePosition(a, tableMsg(`{"table":"position","action":"`+action+`","keys":["account","symbol","currency"],"data":[`+data+`]}`)))
+	}
+	execution := func(id string, qty float64, at string) {
+		e := Execution{ExecID: id, OrderID: id, ClOrdID: newClOrdID(PurposeQuote), Symbol: "XBTUSD", Side: "Buy", ExecType: "Trade", LastQty: qty, LastPx: 6500, TransactTime: at}
+		assert.Nil(t, bm.handleExecution(a, &TableMsg{Table: "execution", Action: "insert", Data: []json.RawMessage{mustMarshal(e)}}))
+	}
+	position("partial", `{"account":1,"symbol":"XBTUSD","currency":"XBt","currentQty":0,"timestamp":"2018-10-01T00:00:00.000Z"}`)
+
+	// 成交先到 持仓推送包含该成交后不重复计入
+	execution("1", 100, "2018-10-01T00:00:01.000Z")
+	assert.Equal(t, 100.0, a.position["XBTUSD"].CurrentQty)
+	position("update", `{"account":1,"symbol":"XBTUSD","currency":"XBt","currentQty":100,"timestamp":"2018-10-01T00:00:01.000Z"}`)
+	assert.Equal(t, 100.0, a.position["XBTUSD"].CurrentQty)
+	position("update", `{"account":1,"symbol":"XBTUSD","currency":"XBt","markPrice":6501,"timestamp":"2018-10-01T00:00:02.000Z"}`)
+	assert.Equal(t, 100.0, a.position["XBTUSD"].CurrentQty)
+
+	// 持仓推送先到 之后到达的成交已包含在内
+	position("update", `{"account":1,"symbol":"XBTUSD","currency":"XBt","currentQty":200,"timestamp":"2018-10-01T00:00:03.000Z"}`)
+	execution("2", 100, "2018-10-01T00:00:03.000Z")
+	assert.Equal(t, 200.0, a.position["XBTUSD"].CurrentQty)
+
+	// 早于成交的持仓推送不清除未包含的成交
+	execution("3", 100, "2018-10-01T00:00:05.000Z")
+	position("update", `{"account":1,"symbol":"XBTUSD","currency":"XBt","markPrice":6502,"timestamp":"2018-10-01T00:00:04.000Z"}`)
+	assert.Equal(t, 300.0, a.position["XBTUSD"].CurrentQty)
+
+	// 重复推送的成交按 ExecID 去重
+	execution("3", 100, "2018-10-01T00:00:05.000Z")
+	assert.Equal(t, 300.0, a.position["XBTUSD"].CurrentQty)
+	position("update", `{"account":1,"symbol":"XBTUSD","currency":"XBt","currentQty":300,"timestamp":"2018-10-01T00:00:05.000Z"}`)
+	assert.Equal(t, 300.0, a.position["XBTUSD"].CurrentQty)
+	assert.Empty(t, a.fills.pending)
+}
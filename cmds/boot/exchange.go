@@ -0,0 +1,117 @@
+package boot
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// 与交易所无关的订单标记 用于订单参数 flags 和 Order.Flags 由适配转换为交易所格式
+const (
+	FlagPostOnly   = "postOnly"   // 只做 maker 会立即成交时撤单
+	FlagReduceOnly = "reduceOnly" // 只减仓
+	FlagClose      = "close"      // 平仓
+	FlagLastPrice  = "lastPrice"  // 按最新成交价触发
+	FlagMarkPrice  = "markPrice"  // 按标记价格触发
+	FlagIndexPrice = "indexPrice" // 按指数价格触发
+)
+
+// 订单参数 ordType 缺省为限价单
+const (
+	OrdLimit     = "limit"
+	OrdStop      = "stop"      // 触发后市价成交
+	OrdStopLimit = "stopLimit" // 触发后按 price 挂限价单
+)
+
+var (
+	exchanges map[string]func() Exchange
+	exchange  Exchange
+)
+
+type (
+	// 交易所适配 策略和账户逻辑只依赖该接口
+	// 线路格式 签名 订阅方式由适配实现 收到的行情和账户数据解码后交给 events.go 中的处理函数
+	// 订单参数沿用 symbol side orderQty price stopPx clOrdID 等字段名 ordType 和 flags 取上面的常量 由适配转换为交易所格式
+	Exchange interface {
+		Name() string
+
+		// Connect 建立连接并认证账户
+		Connect(accounts []*Account) error
+
+		// Subscribe 订阅 symbols 的盘口 成交 以及已认证账户的订单 持仓 成交 全部确认后返回
+		Subscribe(symbols []string) error
+
+		// Serve 保持连接并按 Watch 间隔触发定时任务 直到 stop 关闭或连接断开
+		Serve(stop <-chan struct{}) error
+
+		PlaceOrder(a *Account, params map[string]interface{}) error
+		AmendOrder(a *Account, params map[string]interface{}) error
+		CancelOrder(a *Account, params map[string]interface{}) error
+		SetLeverage(a *Account, symbol string, leverage float64) error
+
+		// 拉取账户当前状态 连接前用于初始化账户
+		Orders(a *Account) ([]Order, error)
+		Positions(a *Account) ([]Position, error)
+	}
+
+	// 可记录原始帧的适配 记录的帧用于回测
+	recorder interface {
+		Record(w io.Writer)
+	}
+)
+
+var (
+	// 配置中的订单标记 不区分大小写 兼容 BitMEX execInst 的写法
+	flagAliases = map[string]string{
+		"postonly":                 FlagPostOnly,
+		"participatedonotinitiate": FlagPostOnly,
+		"reduceonly":               FlagReduceOnly,
+		"close":                    FlagClose,
+		"lastprice":                FlagLastPrice,
+		"markprice":                FlagMarkPrice,
+		"indexprice":               FlagIndexPrice,
+	}
+)
+
+func init() {
+	exchanges = map[string]func() Exchange{
+		"bitmex": newBitmex,
+		"mock":   func() Exchange { return NewMockExchange() },
+	}
+	exchange = newBitmex()
+}
+
+// newExchange create exchange adapter by ExchangeConfig.Name
+func newExchange() (Exchange, error) {
+	create, ok := exchanges[Conf.ExchangeConfig.Name]
+	if !ok {
+		return nil, fmt.Errorf("unknown exchange %s", Conf.ExchangeConfig.Name)
+	}
+	return create(), nil
+}
+
+// parseFlags parse comma separated order flags, unknown ones are dropped
+func parseFlags(s string) (flags []string) {
+	for _, v := range strings.Split(s, ",") {
+		if flag, ok := flagAliases[strings.ToLower(strings.TrimSpace(v))]; ok && !hasFlag(flags, flag) {
+			flags = append(flags, flag)
+		}
+	}
+	return
+}
+
+// hasFlag flags contain flag
+func hasFlag(flags []string, flag string) bool {
+	for _, v := range flags {
+		if v == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// paramFlags flags of order params
+func paramFlags(params map[string]interface{}) []string {
+	flags, _ := params["flags"].([]string)
+	return flags
+}
@@ -0,0 +1,382 @@
+package boot
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	log "github.com/sirupsen/logrus"
+	"math"
+	"time"
+)
+
+type (
+	// 持仓批次 每笔开仓成交一个 各自维护止盈止损单
+	// 反向成交先按开仓顺序冲抵已有批次 剩余部分才开新批次
+	Lot struct {
+		ID         string
+		Entry      string // 开仓订单 orderID
+		Symbol     string
+		Side       string  // 开仓方向
+		Price      float64 // 开仓价
+		Qty        float64 // 剩余数量
+		TakeProfit string  // 止盈单 clOrdID
+		StopLoss   string  // 止损单 clOrdID
+		Placed     time.Time
+		Best       float64 // 移动止损记录的最优价
+		Stopped    bool
+	}
+
+	exitManager struct {
+		account   *Account
+		lots      map[string]*Lot
+		seq       []string // 批次开仓顺序
+		byClOrdID map[string]string
+		byEntry   map[string]string
+	}
+)
+
+func newExitManager(a *Account) *exitManager {
+	return &exitManager{
+		account:   a,
+		lots:      make(map[string]*Lot),
+		byClOrdID: make(map[string]string),
+		byEntry:   make(map[string]string),
+	}
+}
+
+// opposite side of s
+func opposite(side string) string {
+	if side == "Buy" {
+		return "Sell"
+	}
+	return "Buy"
+}
+
+// onExecution open or grow lot on entry fill, reduce lot on exit fill, net opposite fills against open lots
+func (m *exitManager) onExecution(e Execution) {
+	if e.ExecType != "Trade" || e.LastQty <= 0 {
+		return
+	}
+
+	switch purposeOf(e.ClOrdID) {
+	case PurposeTakeProfit, PurposeStopLoss:
+		m.reduce(e)
+	case PurposeUnwind:
+		m.net(e)
+	case PurposeHedge:
+	default:
+		// 价差做市的报价腿由补腿对冲 不单独止盈止损
+		if m.account.basisLeg(e.Symbol) {
+			return
+		}
+		m.open(e)
+	}
+}
+
+// net reduce open lots on the other side by fill in opening order, return qty left
+func (m *exitManager) net(e Execution) float64 {
+	qty := e.LastQty
+	for _, id := range append([]string(nil), m.seq...) {
+		lot := m.lots[id]
+		if qty <= 0 {
+			break
+		}
+		if lot.Symbol != e.Symbol || lot.Side == e.Side {
+			continue
+		}
+		n := math.Min(qty, lot.Qty)
+		lot.Qty -= n
+		qty -= n
+		log.Infof("%s lot %s netted by %v at %v, %v left", lot.Symbol, lot.ID, n, e.LastPx, lot.Qty)
+		if lot.Qty <= 0 {
+			m.close(lot, "")
+		} else {
+			m.resize(lot, "")
+		}
+	}
+	return qty
+}
+
+func (m *exitManager) open(e Execution) {
+	// 同一订单的后续部分成交并入已有批次
+	if lot, ok := m.lots[m.byEntry[e.OrderID]]; ok {
+		lot.Price = (lot.Price*lot.Qty + e.LastPx*e.LastQty) / (lot.Qty + e.LastQty)
+		lot.Qty += e.LastQty
+		log.Infof("%s lot %s grown by %v at %v, %v in total", lot.Symbol, lot.ID, e.LastQty, e.LastPx, lot.Qty)
+		m.resize(lot, "")
+		return
+	}
+
+	qty := m.net(e)
+	if qty <= 0 {
+		return
+	}
+
+	b := make([]byte, 4)
+	rand.Read(b)
+	lot := &Lot{
+		ID:     hex.EncodeToString(b),
+		Entry:  e.OrderID,
+		Symbol: e.Symbol,
+		Side:   e.Side,
+		Price:  e.LastPx,
+		Qty:    qty,
+		Best:   e.LastPx,
+	}
+	m.lots[lot.ID] = lot
+	m.seq = append(m.seq, lot.ID)
+	m.byEntry[lot.Entry] = lot.ID
+	log.Infof("%s lot %s opened, %s %v at %v", lot.Symbol, lot.ID, lot.Side, lot.Qty, lot.Price)
+
+	m.placeTakeProfit(lot, m.takeProfitPrice(lot))
+	m.placeStop(lot)
+}
+
+// takeProfitPrice spread away from entry on tick, take the book only if the touch is already through it
+func (m *exitManager) takeProfitPrice(lot *Lot) float64 {
+	t := m.account.trading()
+	tick := t.PriceUint
+	spread := spreadTicks(t, signalsFor(lot.Symbol).Stats(time.Now())) * tick
+	book := orderBook10[lot.Symbol]
+	if lot.Side == "Buy" {
+		price := lot.Price + spread
+		if tick > 0 {
+			price = math.Ceil(price/tick) * tick
+		}
+		if len(book.Bids) > 0 && price <= book.Bids[0][0] {
+			price = book.Bids[0][0]
+		}
+		return price
+	}
+	price := lot.Price - spread
+	if tick > 0 {
+		price = math.Floor(price/tick) * tick
+	}
+	if len(book.Asks) > 0 && price >= book.Asks[0][0] {
+		price = book.Asks[0][0]
+	}
+	return price
+}
+
+func (m *exitManager) link(lot *Lot, purpose string) string {
+	clOrdID := newClOrdID(purpose)
+	m.byClOrdID[clOrdID] = lot.ID
+	return clOrdID
+}
+
+func (m *exitManager) placeTakeProfit(lot *Lot, price float64) {
+	if lot.TakeProfit != "" {
+		delete(m.byClOrdID, lot.TakeProfit)
+	}
+	lot.TakeProfit = m.link(lot, PurposeTakeProfit)
+	lot.Placed = time.Now()
+
+	params := make(map[string]interface{})
+	params["symbol"] = lot.Symbol
+	params["side"] = opposite(lot.Side)
+	params["orderQty"] = lot.Qty
+	params["price"] = price
+	params["clOrdID"] = lot.TakeProfit
+	m.account.submit(Operate{"create", lot.Symbol, params})
+	log.Infof("%s take profit order to be created at %v, qty is %v", params["side"], price, lot.Qty)
+}
+
+// placeStop exchange side stop or stop limit order, trailing stop is kept locally
+func (m *exitManager) placeStop(lot *Lot) {
+	distance := float64(Conf.Exit.StopLoss) * m.account.trading().PriceUint
+	stopPx := lot.Price - distance
+	if lot.Side == "Sell" {
+		stopPx = lot.Price + distance
+	}
+
+	params := make(map[string]interface{})
+	params["symbol"] = lot.Symbol
+	params["side"] = opposite(lot.Side)
+	params["orderQty"] = lot.Qty
+	params["stopPx"] = stopPx
+
+	switch Conf.Exit.StopType {
+	case "stop":
+		params["ordType"] = OrdStop
+	case "stoplimit":
+		offset := float64(Conf.Exit.StopLimitOffset) * m.account.trading().PriceUint
+		params["ordType"] = OrdStopLimit
+		params["price"] = stopPx - offset
+		if lot.Side == "Sell" {
+			params["price"] = stopPx + offset
+		}
+	default:
+		return
+	}
+
+	if lot.StopLoss != "" {
+		delete(m.byClOrdID, lot.StopLoss)
+	}
+	lot.StopLoss = m.link(lot, PurposeStopLoss)
+	params["clOrdID"] = lot.StopLoss
+	m.account.submit(Operate{"create", lot.Symbol, params})
+	log.Infof("%s stop order to be created at %v, qty is %v", params["side"], stopPx, lot.Qty)
+}
+
+// reduce lot by exit fill, close it when nothing left
+func (m *exitManager) reduce(e Execution) {
+	lot, ok := m.lots[m.byClOrdID[e.ClOrdID]]
+	if !ok {
+		return
+	}
+	lot.Qty -= e.LastQty
+	log.Infof("%s lot %s reduced by %v at %v, %v left", lot.Symbol, lot.ID, e.LastQty, e.LastPx, lot.Qty)
+
+	if lot.Qty <= 0 {
+		m.close(lot, e.ClOrdID)
+		return
+	}
+
+	// 另一张退出单同步减量
+	m.resize(lot, e.ClOrdID)
+}
+
+// resize amend exit orders of lot to its remaining qty except the one given
+func (m *exitManager) resize(lot *Lot, except string) {
+	for _, clOrdID := range []string{lot.TakeProfit, lot.StopLoss} {
+		if clOrdID == "" || clOrdID == except {
+			continue
+		}
+		params := make(map[string]interface{})
+		params["origClOrdID"] = clOrdID
+		params["leavesQty"] = lot.Qty
+		m.account.submit(Operate{"amend", lot.Symbol, params})
+	}
+}
+
+// close cancel remaining exit orders of lot except the one given
+func (m *exitManager) close(lot *Lot, except string) {
+	for _, clOrdID := range []string{lot.TakeProfit, lot.StopLoss} {
+		if clOrdID == "" {
+			continue
+		}
+		delete(m.byClOrdID, clOrdID)
+		if clOrdID == except {
+			continue
+		}
+		params := make(map[string]interface{})
+		params["clOrdID"] = clOrdID
+		m.account.submit(Operate{"cancel", lot.Symbol, params})
+	}
+	delete(m.lots, lot.ID)
+	delete(m.byEntry, lot.Entry)
+	for i, id := range m.seq {
+		if id == lot.ID {
+			m.seq = append(m.seq[:i], m.seq[i+1:]...)
+			break
+		}
+	}
+	log.Infof("%s lot %s closed", lot.Symbol, lot.ID)
+}
+
+// onPostOnlyCanceled take profit crossed the book, put it back at the passive best price, return the new clOrdID
+func (m *exitManager) onPostOnlyCanceled(o Order) (string, bool) {
+	lot, ok := m.lots[m.byClOrdID[o.ClOrdID]]
+	if !ok || lot.TakeProfit != o.ClOrdID {
+		return "", false
+	}
+	book := orderBook10[lot.Symbol]
+	if len(book.Bids) == 0 || len(book.Asks) == 0 {
+		return "", true
+	}
+	price := book.Asks[0][0]
+	if lot.Side == "Sell" {
+		price = book.Bids[0][0]
+	}
+	m.placeTakeProfit(lot, price)
+	return lot.TakeProfit, true
+}
+
+// trail local trailing stop, close lot aggressively once price retraces StopLoss ticks from best
+func (m *exitManager) trail(book OrderBook10) {
+	if Conf.Exit.StopType != "trailing" || len(book.Bids) == 0 || len(book.Asks) == 0 {
+		return
+	}
+	distance := float64(Conf.Exit.StopLoss) * m.account.trading().PriceUint
+	for _, lot := range m.lots {
+		if lot.Symbol != book.Symbol || lot.Stopped {
+			continue
+		}
+
+		var price float64
+		if lot.Side == "Buy" {
+			if book.Bids[0][0] > lot.Best {
+				lot.Best = book.Bids[0][0]
+			}
+			if book.Bids[0][0] > lot.Best-distance {
+				continue
+			}
+			price = book.Bids[0][0]
+		} else {
+			if book.Asks[0][0] < lot.Best {
+				lot.Best = book.Asks[0][0]
+			}
+			if book.Asks[0][0] < lot.Best+distance {
+				continue
+			}
+			price = book.Asks[0][0]
+		}
+
+		lot.Stopped = true
+		if lot.StopLoss != "" {
+			delete(m.byClOrdID, lot.StopLoss)
+		}
+		lot.StopLoss = m.link(lot, PurposeStopLoss)
+		params := make(map[string]interface{})
+		params["symbol"] = lot.Symbol
+		params["side"] = opposite(lot.Side)
+		params["orderQty"] = lot.Qty
+		params["price"] = price
+		params["clOrdID"] = lot.StopLoss
+		m.account.submit(Operate{"create", lot.Symbol, params})
+		alert("%s lot %s trailing stop hit, best %v, closing %v at %v", lot.Symbol, lot.ID, lot.Best, lot.Qty, price)
+	}
+}
+
+// maintain put back exit orders that are gone or out of size, drop lots once the position is flat
+func (m *exitManager) maintain(orders []Order, now time.Time) {
+	working := make(map[string]Order)
+	for _, v := range orders {
+		if isWorking(v) {
+			working[v.ClOrdID] = v
+		}
+	}
+
+	for _, lot := range m.lots {
+		if now.Sub(lot.Placed) < amendTimeout() {
+			continue
+		}
+		if p, ok := m.account.position[lot.Symbol]; ok && p.CurrentQty == 0 {
+			m.close(lot, "")
+			continue
+		}
+		if _, ok := working[lot.StopLoss]; lot.Stopped && !ok {
+			// 止损单未成交已失效 下次行情更新时重新触发
+			lot.Stopped = false
+			continue
+		}
+		if lot.Stopped {
+			continue
+		}
+		if _, ok := working[lot.StopLoss]; !ok && (Conf.Exit.StopType == "stop" || Conf.Exit.StopType == "stoplimit") {
+			log.Infof("%s lot %s stop order missing", lot.Symbol, lot.ID)
+			m.placeStop(lot)
+			lot.Placed = now
+		}
+		tp, ok := working[lot.TakeProfit]
+		if !ok {
+			log.Infof("%s lot %s take profit order missing", lot.Symbol, lot.ID)
+			m.placeTakeProfit(lot, m.takeProfitPrice(lot))
+			continue
+		}
+		if tp.LeavesQty != lot.Qty {
+			log.Infof("%s lot %s take profit qty %v, expect %v", lot.Symbol, lot.ID, tp.LeavesQty, lot.Qty)
+			m.resize(lot, "")
+			lot.Placed = now
+		}
+	}
+}
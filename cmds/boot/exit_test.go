@@ -0,0 +1,193 @@
+package boot
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// drain queued operations of account
+func drain(a *Account) (ops []Operate) {
+	for {
+		select {
+		case op := <-a.operate:
+			ops = append(ops, op)
+		default:
+			return
+		}
+	}
+}
+
+func TestExits(t *testing.T) {
+	defer func() {
+		Conf = Default()
+		orderBook10 = make(map[string]OrderBook10)
+	}()
+	Conf.Exit.StopType = "stop"
+	Conf.Exit.StopLoss = 20
+	Conf.Throttle = &Throttle{100, 1000, 100, 1000, 100, 1000, 60}
+	a := newAccount(&AccountConfig{Name: "test", Trading: &Trading{Spread: 2, PriceUint: 0.5}})
+	m := a.exits
+	orderBook10["XBTUSD"] = OrderBook10{Symbol: "XBTUSD", Bids: []Bid{{6500, 100}}, Asks: []Ask{{6501, 100}}}
+
+	// 部分成交开仓 止盈在价差之内时挂在目标价
+	entry := newClOrdID(PurposeQuote)
+	m.onExecution(Execution{ExecID: "1", OrderID: "o1", ClOrdID: entry, Symbol: "XBTUSD", Side: "Buy", ExecType: "Trade", LastQty: 50, LastPx: 6500})
+	ops := drain(a)
+	assert.Len(t, ops, 2)
+	tp, sl := ops[0], ops[1]
+	assert.Equal(t, PurposeTakeProfit, purposeOf(tp.Params["clOrdID"].(string)))
+	assert.Equal(t, "Sell", tp.Params["side"])
+	assert.Equal(t, 6501.0, tp.Params["price"])
+	assert.Equal(t, 50.0, tp.Params["orderQty"])
+	assert.Equal(t, OrdStop, sl.Params["ordType"])
+	assert.Equal(t, 6490.0, sl.Params["stopPx"])
+	assert.Equal(t, 50.0, sl.Params["orderQty"])
+
+	// 同一订单继续成交 批次加量 两张退出单改量
+	m.onExecution(Execution{ExecID: "2", OrderID: "o1", ClOrdID: entry, Symbol: "XBTUSD", Side: "Buy", ExecType: "Trade", LastQty: 50, LastPx: 6499})
+	ops = drain(a)
+	assert.Len(t, ops, 2)
+	for _, op := range ops {
+		assert.Equal(t, "amend", op.Action)
+		assert.Equal(t, 100.0, op.Params["leavesQty"])
+	}
+	assert.Len(t, m.lots, 1)
+
+	// 止盈部分成交 止损改为剩余数量
+	tpID, slID := tp.Params["clOrdID"].(string), sl.Params["clOrdID"].(string)
+	m.onExecution(Execution{ExecID: "3", OrderID: "o2", ClOrdID: tpID, Symbol: "XBTUSD", Side: "Sell", ExecType: "Trade", LastQty: 30, LastPx: 6501})
+	ops = drain(a)
+	assert.Equal(t, []Operate{{"amend", "XBTUSD", map[string]interface{}{"origClOrdID": slID, "leavesQty": 70.0}}}, ops)
+
+	// 止盈全部成交 撤掉止损
+	m.onExecution(Execution{ExecID: "4", OrderID: "o2", ClOrdID: tpID, Symbol: "XBTUSD", Side: "Sell", ExecType: "Trade", LastQty: 70, LastPx: 6501})
+	ops = drain(a)
+	assert.Equal(t, []Operate{{"cancel", "XBTUSD", map[string]interface{}{"clOrdID": slID}}}, ops)
+	assert.Empty(t, m.lots)
+	assert.Empty(t, m.byClOrdID)
+
+	// 反向成交先冲抵已有批次 剩余部分才开新批次
+	m.onExecution(Execution{ExecID: "5", OrderID: "o3", ClOrdID: newClOrdID(PurposeQuote), Symbol: "XBTUSD", Side: "Buy", ExecType: "Trade", LastQty: 100, LastPx: 6500})
+	ops = drain(a)
+	tpID, slID = ops[0].Params["clOrdID"].(string), ops[1].Params["clOrdID"].(string)
+	m.onExecution(Execution{ExecID: "6", OrderID: "o4", ClOrdID: newClOrdID(PurposeQuote), Symbol: "XBTUSD", Side: "Sell", ExecType: "Trade", LastQty: 30, LastPx: 6501})
+	ops = drain(a)
+	assert.Len(t, ops, 2)
+	for _, op := range ops {
+		assert.Equal(t, "amend", op.Action)
+		assert.Equal(t, 70.0, op.Params["leavesQty"])
+	}
+	m.onExecution(Execution{ExecID: "7", OrderID: "o5", ClOrdID: newClOrdID(PurposeQuote), Symbol: "XBTUSD", Side: "Sell", ExecType: "Trade", LastQty: 100, LastPx: 6501})
+	ops = drain(a)
+	assert.Equal(t, []Operate{
+		{"cancel", "XBTUSD", map[string]interface{}{"clOrdID": tpID}},
+		{"cancel", "XBTUSD", map[string]interface{}{"clOrdID": slID}},
+	}, ops[:2])
+	assert.Len(t, ops, 4)
+	assert.Equal(t, "Buy", ops[2].Params["side"])
+	assert.Equal(t, 30.0, ops[2].Params["orderQty"])
+	assert.Len(t, m.lots, 1)
+	assert.Len(t, m.seq, 1)
+
+	// 减仓单成交同样冲抵
+	m.onExecution(Execution{ExecID: "8", OrderID: "o6", ClOrdID: newClOrdID(PurposeUnwind), Symbol: "XBTUSD", Side: "Buy", ExecType: "Trade", LastQty: 30, LastPx: 6500})
+	assert.Len(t, drain(a), 2)
+	assert.Empty(t, m.lots)
+	assert.Empty(t, m.seq)
+}
+
+func TestTakeProfitPrice(t *testing.T) {
+	defer func() { orderBook10 = make(map[string]OrderBook10) }()
+	a := newAccount(&AccountConfig{Name: "test", Trading: &Trading{Spread: 2, PriceUint: 0.5}})
+	orderBook10["XBTUSD"] = OrderBook10{Symbol: "XBTUSD", Bids: []Bid{{6500, 100}}, Asks: []Ask{{6502, 100}}}
+
+	// 目标价在价差之内 被动挂单 不在买一卖出
+	assert.Equal(t, 6501.0, a.exits.takeProfitPrice(&Lot{Symbol: "XBTUSD", Side: "Buy", Price: 6500}))
+	assert.Equal(t, 6501.0, a.exits.takeProfitPrice(&Lot{Symbol: "XBTUSD", Side: "Sell", Price: 6502}))
+
+	// 盘口已越过目标价 直接成交
+	assert.Equal(t, 6500.0, a.exits.takeProfitPrice(&Lot{Symbol: "XBTUSD", Side: "Buy", Price: 6498}))
+	assert.Equal(t, 6502.0, a.exits.takeProfitPrice(&Lot{Symbol: "XBTUSD", Side: "Sell", Price: 6504}))
+}
+
+func TestTrailingStop(t *testing.T) {
+	defer func() {
+		Conf = Default()
+		orderBook10 = make(map[string]OrderBook10)
+	}()
+	Conf.Exit.StopType = "trailing"
+	Conf.Exit.StopLoss = 4
+	a := newAccount(&AccountConfig{Name: "test", Trading: &Trading{Spread: 2, PriceUint: 0.5}})
+	m := a.exits
+	orderBook10["XBTUSD"] = OrderBook10{Symbol: "XBTUSD", Bids: []Bid{{6500, 100}}, Asks: []Ask{{6501, 100}}}
+
+	m.onExecution(Execution{ExecID: "1", OrderID: "o1", ClOrdID: newClOrdID(PurposeQuote), Symbol: "XBTUSD", Side: "Buy", ExecType: "Trade", LastQty: 100, LastPx: 6500})
+	// 移动止损不挂交易所止损单
+	assert.Len(t, drain(a), 1)
+
+	book := func(bid float64) OrderBook10 {
+		return OrderBook10{Symbol: "XBTUSD", Bids: []Bid{{bid, 100}}, Asks: []Ask{{bid + 0.5, 100}}}
+	}
+	m.trail(book(6510))
+	m.trail(book(6508.5))
+	assert.Empty(t, drain(a))
+
+	// 从最优价回撤 4 个价格单位 在买一平仓
+	m.trail(book(6508))
+	ops := drain(a)
+	assert.Len(t, ops, 1)
+	assert.Equal(t, PurposeStopLoss, purposeOf(ops[0].Params["clOrdID"].(string)))
+	assert.Equal(t, "Sell", ops[0].Params["side"])
+	assert.Equal(t, 6508.0, ops[0].Params["price"])
+	assert.Equal(t, 100.0, ops[0].Params["orderQty"])
+
+	// 只触发一次
+	m.trail(book(6500))
+	assert.Empty(t, drain(a))
+
+	// 止损单失效后重新触发
+	for _, lot := range m.lots {
+		lot.Placed = time.Time{}
+	}
+	a.position["XBTUSD"] = Position{Symbol: "XBTUSD", CurrentQty: 100}
+	m.maintain(nil, time.Now())
+	m.trail(book(6500))
+	assert.Len(t, drain(a), 1)
+}
+
+func TestTakeProfitSpread(t *testing.T) {
+	defer func() {
+		Conf = Default()
+		orderBook10 = make(map[string]OrderBook10)
+		signals = make(map[string]*Signals)
+	}()
+	tr := &Trading{Symbol: []string{"XBTUSD"}, Spread: 2, PriceUint: 0.5, SpreadMode: "trade", SpreadFactor: 1, SpreadWindow: 4, MinSpread: 1, MaxSpread: 20}
+	a := newAccount(&AccountConfig{Name: "test", Trading: tr})
+	orderBook10["XBTUSD"] = OrderBook10{Symbol: "XBTUSD", Bids: []Bid{{6500, 100}}, Asks: []Ask{{6501, 100}}}
+	lot := &Lot{Symbol: "XBTUSD", Side: "Buy", Price: 6500}
+
+	// 没有成交时用固定价差
+	assert.Equal(t, 6501.0, a.exits.takeProfitPrice(lot))
+
+	// 成交价波动率 1 价差 1 * 1 * sqrt(4) = 2 即 4 个价格单位
+	now := time.Now()
+	signalsFor("XBTUSD").OnTrade(Trade{Price: 6500, Size: 100}, now)
+	signalsFor("XBTUSD").OnTrade(Trade{Price: 6501, Size: 100}, now.Add(time.Second))
+	assert.Equal(t, 6502.0, a.exits.takeProfitPrice(lot))
+
+	// 价差不是整数个价格单位时 远离开仓价取整
+	tr.SpreadFactor = 0.6
+	assert.Equal(t, 6501.5, a.exits.takeProfitPrice(lot))
+	assert.Equal(t, 6498.5, a.exits.takeProfitPrice(&Lot{Symbol: "XBTUSD", Side: "Sell", Price: 6500}))
+
+	// 按成交价波动率时自动订阅成交
+	saved := accounts
+	defer func() { accounts = saved }()
+	accounts = []*Account{a}
+	assert.Contains(t, topics(symbols()), "trade:XBTUSD")
+	tr.SpreadMode = "mid"
+	assert.NotContains(t, topics(symbols()), "trade:XBTUSD")
+	Conf.Quoting.Mode = "avellaneda"
+	assert.Contains(t, topics(symbols()), "trade:XBTUSD")
+}
@@ -0,0 +1,69 @@
+package boot
+
+import (
+	"math"
+)
+
+// 报价公允价 Quoting.Fair
+// book: 直接跟随最优买卖价 / mid: 中间价 / microprice: 按挂单量加权的中间价
+// mark: instrument 的标记价格 / index: Quoting.Index 指数(如 .BXBT)的最新价
+// 非 book 时在公允价两侧各半个价差报价 价差见 spread.go
+
+// fairValue fair price of snapshot, false if not available
+func fairValue(snap Snapshot) (float64, bool) {
+	book := snap.Book
+	if len(book.Bids) == 0 || len(book.Asks) == 0 {
+		return 0, false
+	}
+	bid, ask := book.Bids[0], book.Asks[0]
+
+	switch Conf.Quoting.Fair {
+	case "mid":
+		return (bid[0] + ask[0]) / 2, true
+	case "microprice":
+		if len(bid) < 2 || len(ask) < 2 || bid[1]+ask[1] <= 0 {
+			return (bid[0] + ask[0]) / 2, true
+		}
+		// 买单量大时价格更可能上涨 靠近卖价
+		return (bid[0]*ask[1] + ask[0]*bid[1]) / (bid[1] + ask[1]), true
+	case "mark":
+		return snap.Instrument.MarkPrice, snap.Instrument.MarkPrice > 0
+	case "index":
+		return snap.Index.LastPrice, snap.Index.LastPrice > 0
+	}
+	return 0, false
+}
+
+// halfSpread half of quoted spread in price
+func halfSpread(snap Snapshot) float64 {
+	t := snap.trading()
+	return spreadTicks(t, snap.Signals) * t.PriceUint / 2
+}
+
+// touch bid and ask to quote around, best bid and ask of book by default
+func touch(snap Snapshot) (bid, ask float64, ok bool) {
+	book := snap.Book
+	if Conf.Quoting.Fair == "" || Conf.Quoting.Fair == "book" {
+		bid, ask = book.Bids[0][0], book.Asks[0][0]
+		if t := snap.trading(); t.adaptive() {
+			// 波动大时从盘口向外退到中间价两侧各半个价差
+			mid := (bid + ask) / 2
+			half := halfSpread(snap)
+			bid = math.Min(bid, math.Floor((mid-half)/t.PriceUint)*t.PriceUint)
+			ask = math.Max(ask, math.Ceil((mid+half)/t.PriceUint)*t.PriceUint)
+		}
+		return bid, ask, true
+	}
+
+	fair, ok := fairValue(snap)
+	if !ok {
+		return
+	}
+	tick := snap.trading().PriceUint
+	half := halfSpread(snap)
+
+	// 远离公允价取整 不穿过对手价
+	bid = math.Min(math.Floor((fair-half)/tick)*tick, book.Asks[0][0]-tick)
+	ask = math.Max(math.Ceil((fair+half)/tick)*tick, book.Bids[0][0]+tick)
+	return bid, ask, true
+}
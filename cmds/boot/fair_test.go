@@ -0,0 +1,41 @@
+package boot
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFairValue(t *testing.T) {
+	defer func() { Conf = Default() }()
+
+	snap := Snapshot{
+		Book:       OrderBook10{Bids: []Bid{{6500, 300}}, Asks: []Ask{{6501, 100}}},
+		Instrument: Instrument{MarkPrice: 6510},
+		Trading:    &Trading{UnitQty: 100, Spread: 4, PriceUint: 0.5},
+	}
+
+	Conf.Quoting.Fair = "book"
+	assert.Equal(t, []Quote{{"Buy", 6500, 100}, {"Sell", 6501, 100}}, topStrategy{}.Quotes(snap))
+
+	Conf.Quoting.Fair = "mid"
+	assert.Equal(t, []Quote{{"Buy", 6499.5, 100}, {"Sell", 6501.5, 100}}, topStrategy{}.Quotes(snap))
+
+	// 买单量大 公允价靠近卖价
+	Conf.Quoting.Fair = "microprice"
+	fair, ok := fairValue(snap)
+	assert.True(t, ok)
+	assert.Equal(t, 6500.75, fair)
+	assert.Equal(t, []Quote{{"Buy", 6499.5, 100}, {"Sell", 6502, 100}}, topStrategy{}.Quotes(snap))
+
+	// 不穿过对手价
+	Conf.Quoting.Fair = "mark"
+	assert.Equal(t, []Quote{{"Buy", 6500.5, 100}, {"Sell", 6511, 100}}, topStrategy{}.Quotes(snap))
+
+	// 没有指数价格不报价
+	Conf.Quoting.Fair = "index"
+	assert.Empty(t, topStrategy{}.Quotes(snap))
+	snap.Index = Instrument{Symbol: ".BXBT", LastPrice: 6490}
+	assert.Equal(t, []Quote{{"Buy", 6489, 100}, {"Sell", 6500.5, 100}}, topStrategy{}.Quotes(snap))
+	assert.Contains(t, topics([]string{"XBTUSD"}), "instrument:.BXBT")
+	assert.NotContains(t, topics([]string{"XBTUSD"}), "orderBook10:.BXBT")
+}
@@ -0,0 +1,79 @@
+package boot
+
+import (
+	"time"
+)
+
+type (
+	// 已成交 尚未包含在持仓推送中的成交
+	// position 与 execution 两张表的推送没有先后保证 持仓按推送时间判断是否已包含成交
+	fillTracker struct {
+		reported map[string]time.Time // symbol -> 最近一次持仓推送时间
+		pending  map[string][]pendingFill
+	}
+
+	pendingFill struct {
+		Qty  float64
+		Time time.Time // 成交时间 未知为零值
+	}
+)
+
+func newFillTracker() *fillTracker {
+	return &fillTracker{
+		reported: make(map[string]time.Time),
+		pending:  make(map[string][]pendingFill),
+	}
+}
+
+// fillTime transact time of execution, zero if unknown
+func fillTime(e Execution) time.Time {
+	s := e.TransactTime
+	if s == "" {
+		s = e.Timestamp
+	}
+	t, _ := time.Parse(time.RFC3339Nano, s)
+	return t
+}
+
+// add record fill of qty, false if the reported position already includes it
+func (f *fillTracker) add(e Execution, qty float64) bool {
+	t := fillTime(e)
+	if r, ok := f.reported[e.Symbol]; ok && !t.IsZero() && !r.IsZero() && !t.After(r) {
+		return false
+	}
+	f.pending[e.Symbol] = append(f.pending[e.Symbol], pendingFill{qty, t})
+	return true
+}
+
+// caught position reported, drop fills it includes and return qty of fills still pending
+func (f *fillTracker) caught(p Position) (delta float64) {
+	r, _ := time.Parse(time.RFC3339Nano, p.Timestamp)
+	f.reported[p.Symbol] = r
+
+	// 推送时间未知时以持仓为准
+	var pending []pendingFill
+	for _, v := range f.pending[p.Symbol] {
+		if r.IsZero() || v.Time.IsZero() || !v.Time.After(r) {
+			continue
+		}
+		pending = append(pending, v)
+		delta += v.Qty
+	}
+	if len(pending) == 0 {
+		delete(f.pending, p.Symbol)
+	} else {
+		f.pending[p.Symbol] = pending
+	}
+	return
+}
+
+// reset forget symbol, all symbols if empty
+func (f *fillTracker) reset(symbol string) {
+	if symbol == "" {
+		f.reported = make(map[string]time.Time)
+		f.pending = make(map[string][]pendingFill)
+		return
+	}
+	delete(f.reported, symbol)
+	delete(f.pending, symbol)
+}
@@ -0,0 +1,121 @@
+package boot
+
+import (
+	log "github.com/sirupsen/logrus"
+	"math"
+	"time"
+)
+
+var (
+	fundings map[string]Funding
+)
+
+type (
+	FundingMsg struct {
+		Table  string    `json:"table"`
+		Action string    `json:"action"`
+		Data   []Funding `json:"data"`
+	}
+
+	// 已结算的资金费
+	Funding struct {
+		Timestamp        string  `json:"timestamp"`
+		Symbol           string  `json:"symbol"`
+		FundingInterval  string  `json:"fundingInterval"`
+		FundingRate      float64 `json:"fundingRate"`
+		FundingRateDaily float64 `json:"fundingRateDaily"`
+	}
+
+	// 下次资金费
+	NextFunding struct {
+		Rate float64   // 下次结算的资金费率 >0 多头支付 <0 空头支付
+		Time time.Time // 下次结算时间
+	}
+)
+
+func init() {
+	fundings = make(map[string]Funding)
+}
+
+// nextFunding next funding of symbol from instrument table
+func nextFunding(symbol string) (f NextFunding, ok bool) {
+	i, ok := instruments[symbol]
+	if !ok || i.FundingTimestamp == "" {
+		return f, false
+	}
+	return NextFunding{i.FundingRate, parseTime(i.FundingTimestamp)}, true
+}
+
+// payingSide side that pays funding, empty when rate is zero
+func (f NextFunding) payingSide() string {
+	switch {
+	case f.Rate > 0:
+		return "Buy"
+	case f.Rate < 0:
+		return "Sell"
+	}
+	return ""
+}
+
+// active funding is close and large enough for the policy to apply
+func (f NextFunding) active(now time.Time) bool {
+	window := time.Duration(Conf.FundingPolicy.Window) * time.Minute
+	return Conf.FundingPolicy.Policy != "none" &&
+		math.Abs(f.Rate) >= Conf.FundingPolicy.Threshold &&
+		now.Before(f.Time) && f.Time.Sub(now) <= window
+}
+
+// fundingQuotes drop quotes adding to the paying side before funding
+func fundingQuotes(symbol string, quotes []Quote, now time.Time) []Quote {
+	f, ok := nextFunding(symbol)
+	if !ok || !f.active(now) {
+		return quotes
+	}
+	var kept []Quote
+	for _, q := range quotes {
+		if q.Side != f.payingSide() {
+			kept = append(kept, q)
+		}
+	}
+	return kept
+}
+
+// fundingUnwind reduce position on the paying side to FundingPolicy.Keep (0 when flatten) before funding
+func (a *Account) fundingUnwind(symbol string, now time.Time) {
+	f, ok := nextFunding(symbol)
+	if !ok || !f.active(now) {
+		return
+	}
+
+	qty := a.position[symbol].CurrentQty
+	side := f.payingSide()
+	if (side == "Buy" && qty <= 0) || (side == "Sell" && qty >= 0) {
+		return
+	}
+
+	keep := Conf.FundingPolicy.Keep
+	if Conf.FundingPolicy.Policy == "flatten" {
+		keep = 0
+	}
+	excess := math.Abs(qty) - keep
+	if excess <= 0 {
+		return
+	}
+
+	for _, v := range a.order {
+		if v.Symbol == symbol && isWorking(v) && v.Side == opposite(side) && purposeOf(v.ClOrdID) == PurposeUnwind {
+			return
+		}
+	}
+
+	book := orderBook10[symbol]
+	if len(book.Bids) == 0 || len(book.Asks) == 0 {
+		return
+	}
+	q := Quote{opposite(side), book.Asks[0][0], excess}
+	if q.Side == "Buy" {
+		q.Price = book.Bids[0][0]
+	}
+	a.submit(createOp(symbol, q, PurposeUnwind))
+	log.Infof("%s funding rate %v at %s, %s %v at %v", symbol, f.Rate, f.Time.Format(time.RFC3339), q.Side, q.Qty, q.Price)
+}
@@ -0,0 +1,83 @@
+package boot
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestFundingQuotes(t *testing.T) {
+	defer func() {
+		Conf = Default()
+		instruments = make(map[string]Instrument)
+	}()
+
+	now := time.Date(2018, 10, 1, 11, 45, 0, 0, time.UTC)
+	instruments["XBTUSD"] = Instrument{
+		Symbol:           "XBTUSD",
+		FundingRate:      0.001,
+		FundingTimestamp: "2018-10-01T12:00:00.000Z",
+	}
+	quotes := []Quote{{"Buy", 6500, 100}, {"Sell", 6501, 100}}
+
+	// policy off
+	assert.Equal(t, quotes, fundingQuotes("XBTUSD", quotes, now))
+
+	// longs pay, stop bidding
+	Conf.FundingPolicy.Policy = "reduce"
+	assert.Equal(t, quotes[1:], fundingQuotes("XBTUSD", quotes, now))
+
+	// outside window or below threshold
+	assert.Equal(t, quotes, fundingQuotes("XBTUSD", quotes, now.Add(-time.Hour)))
+	Conf.FundingPolicy.Threshold = 0.01
+	assert.Equal(t, quotes, fundingQuotes("XBTUSD", quotes, now))
+}
+
+func TestFundingUnwind(t *testing.T) {
+	defer func() {
+		Conf = Default()
+		instruments = make(map[string]Instrument)
+		orderBook10 = make(map[string]OrderBook10)
+	}()
+
+	now := time.Date(2018, 10, 1, 11, 45, 0, 0, time.UTC)
+	instruments["XBTUSD"] = Instrument{
+		Symbol:           "XBTUSD",
+		FundingRate:      0.001,
+		FundingTimestamp: "2018-10-01T12:00:00.000Z",
+	}
+	orderBook10["XBTUSD"] = OrderBook10{Symbol: "XBTUSD", Bids: []Bid{{6500, 100}}, Asks: []Ask{{6501, 100}}}
+	a := newAccount(&AccountConfig{Name: "test"})
+	a.position["XBTUSD"] = Position{Symbol: "XBTUSD", CurrentQty: 500}
+
+	// policy off
+	a.fundingUnwind("XBTUSD", now)
+	assert.Empty(t, drain(a))
+
+	// longs pay, sell down to Keep at the ask
+	Conf.FundingPolicy.Policy = "reduce"
+	Conf.FundingPolicy.Keep = 200
+	a.fundingUnwind("XBTUSD", now)
+	ops := drain(a)
+	assert.Len(t, ops, 1)
+	assert.Equal(t, "Sell", ops[0].Params["side"])
+	assert.Equal(t, 6501.0, ops[0].Params["price"])
+	assert.Equal(t, 300.0, ops[0].Params["orderQty"])
+	assert.Equal(t, PurposeUnwind, purposeOf(ops[0].Params["clOrdID"].(string)))
+
+	// working unwind order is not duplicated
+	a.order = []Order{{OrderID: "1", ClOrdID: ops[0].Params["clOrdID"].(string), Symbol: "XBTUSD", Side: "Sell", OrdStatus: "New"}}
+	a.fundingUnwind("XBTUSD", now)
+	assert.Empty(t, drain(a))
+
+	// flatten ignores Keep
+	a.order = nil
+	Conf.FundingPolicy.Policy = "flatten"
+	a.fundingUnwind("XBTUSD", now)
+	assert.Equal(t, 500.0, drain(a)[0].Params["orderQty"])
+
+	// shorts receive funding
+	a.position["XBTUSD"] = Position{Symbol: "XBTUSD", CurrentQty: -500}
+	a.fundingUnwind("XBTUSD", now)
+	assert.Empty(t, drain(a))
+}
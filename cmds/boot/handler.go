@@ -4,21 +4,21 @@ import (
 	"encoding/json"
 	log "github.com/sirupsen/logrus"
 	"github.com/tidwall/gjson"
-	"math"
-	"reflect"
+	"time"
 	//"strings"
 )
 
 var (
-	orderBook10 map[string]OrderBook10
-	position    map[string]Position
-	order       []Order
-	operate     chan Operate
+	orderBook10  map[string]OrderBook10
+	spareBooks   map[string]OrderBook10 // 上一次的盘口 复用其缓冲写入下一次更新
+	instruments  map[string]Instrument
+	publicTables *Tables // 行情表 各账户共用
 )
 
 type (
 	Operate struct {
 		Action string
+		Symbol string
 		Params map[string]interface{}
 	}
 
@@ -33,14 +33,104 @@ type (
 		Bids      []Bid  `json:"bids"`
 		Asks      []Ask  `json:"asks"`
 		Timestamp string `json:"timestamp"`
+
+		levels []float64 // Bids 和 Asks 共用的存储
 	}
 	Bid []float64
 	Ask []float64
 
-	PositionMsg struct {
-		Table  string     `json:"table"`
-		Action string     `json:"Action"`
-		Data   []Position `json:"data"`
+	TradeMsg struct {
+		Table  string  `json:"table"`
+		Action string  `json:"action"`
+		Data   []Trade `json:"data"`
+	}
+
+	Trade struct {
+		Timestamp       string  `json:"timestamp"`
+		Symbol          string  `json:"symbol"`
+		Side            string  `json:"side"`
+		Size            float64 `json:"size"`
+		Price           float64 `json:"price"`
+		TickDirection   string  `json:"tickDirection"`
+		TrdMatchID      string  `json:"trdMatchID"`
+		GrossValue      float64 `json:"grossValue"`
+		HomeNotional    float64 `json:"homeNotional"`
+		ForeignNotional float64 `json:"foreignNotional"`
+	}
+
+	QuoteMsg struct {
+		Table  string      `json:"table"`
+		Action string      `json:"action"`
+		Data   []BookQuote `json:"data"`
+	}
+
+	// 最优买卖报价 quote 表
+	BookQuote struct {
+		Timestamp string  `json:"timestamp"`
+		Symbol    string  `json:"symbol"`
+		BidSize   float64 `json:"bidSize"`
+		BidPrice  float64 `json:"bidPrice"`
+		AskPrice  float64 `json:"askPrice"`
+		AskSize   float64 `json:"askSize"`
+	}
+
+	LiquidationMsg struct {
+		Table  string        `json:"table"`
+		Action string        `json:"action"`
+		Data   []Liquidation `json:"data"`
+	}
+
+	// 强平单
+	Liquidation struct {
+		OrderID   string    `json:"orderID"`
+		Symbol    string    `json:"symbol"`
+		Side      string    `json:"side"`
+		Price     float64   `json:"price"`
+		LeavesQty float64   `json:"leavesQty"`
+		Time      time.Time `json:"-"` // 收到的时间
+	}
+
+	InstrumentMsg struct {
+		Table  string            `json:"table"`
+		Action string            `json:"action"`
+		Data   []json.RawMessage `json:"data"`
+	}
+
+	Instrument struct {
+		Symbol                string  `json:"symbol"`
+		RootSymbol            string  `json:"rootSymbol"`
+		State                 string  `json:"state"`
+		Typ                   string  `json:"typ"`
+		Expiry                string  `json:"expiry"`
+		Underlying            string  `json:"underlying"`
+		QuoteCurrency         string  `json:"quoteCurrency"`
+		SettlCurrency         string  `json:"settlCurrency"`
+		ReferenceSymbol       string  `json:"referenceSymbol"`
+		TickSize              float64 `json:"tickSize"`
+		LotSize               float64 `json:"lotSize"`
+		Multiplier            float64 `json:"multiplier"`
+		IsInverse             bool    `json:"isInverse"`
+		InitMargin            float64 `json:"initMargin"`
+		MaintMargin           float64 `json:"maintMargin"`
+		MakerFee              float64 `json:"makerFee"`
+		TakerFee              float64 `json:"takerFee"`
+		FundingBaseSymbol     string  `json:"fundingBaseSymbol"`
+		FundingQuoteSymbol    string  `json:"fundingQuoteSymbol"`
+		FundingPremiumSymbol  string  `json:"fundingPremiumSymbol"`
+		FundingTimestamp      string  `json:"fundingTimestamp"`      // 下次资金费结算时间
+		FundingInterval       string  `json:"fundingInterval"`       // 资金费结算间隔
+		FundingRate           float64 `json:"fundingRate"`           // 下次结算的资金费率
+		IndicativeFundingRate float64 `json:"indicativeFundingRate"` // 再下次结算的预测资金费率
+		MarkMethod            string  `json:"markMethod"`
+		MarkPrice             float64 `json:"markPrice"`
+		IndicativeSettlePrice float64 `json:"indicativeSettlePrice"`
+		FairBasis             float64 `json:"fairBasis"`
+		FairPrice             float64 `json:"fairPrice"`
+		LastPrice             float64 `json:"lastPrice"`
+		BidPrice              float64 `json:"bidPrice"`
+		AskPrice              float64 `json:"askPrice"`
+		MidPrice              float64 `json:"midPrice"`
+		Timestamp             string  `json:"timestamp"`
 	}
 
 	Position struct {
@@ -66,14 +156,10 @@ type (
 		LastPrice            float64 `json:"lastPrice"`            // 最新价格
 	}
 
-	OrderMsg struct {
-		Table  string  `json:"table"`
-		Action string  `json:"Action"`
-		Data   []Order `json:"data"`
-	}
 	Order struct {
 		Account               float64 `json:"account"`
 		OrderID               string  `json:"orderID"`
+		ClOrdID               string  `json:"clOrdID"`
 		Symbol                string  `json:"symbol"`
 		Side                  string  `json:"side"`
 		SimpleOrderQty        float64 `json:"simpleOrderQty"`
@@ -103,12 +189,10 @@ type (
 		Text                  string  `json:"text"`
 		TransactTime          string  `json:"transactTime"`
 		Timestamp             string  `json:"timestamp"`
-	}
 
-	ExecutionMsg struct {
-		Table  string      `json:"table"`
-		Action string      `json:"Action"`
-		Data   []Execution `json:"data"`
+		// 由适配设置 与交易所无关
+		Flags   []string `json:"-"` // 订单标记
+		Crossed bool     `json:"-"` // 被动单会立即成交 被交易所撤销
 	}
 
 	Execution struct {
@@ -163,428 +247,276 @@ type (
 )
 
 func init() {
-	operate = make(chan Operate, 1)
-
-	go func() {
-		for {
-			op := <-operate
-			switch op.Action {
-			case "create":
-				if err := createOrder(op.Params); err != nil {
-					log.Info(err)
-				}
-			case "amend":
-				if err := amendOrder(op.Params); err != nil {
-					log.Info(err)
-				}
-			case "cancel":
-				if err := cancelOrder(op.Params); err != nil {
-					log.Info(err)
-				}
-			default:
-				log.Info("not supported action")
-			}
-		}
-	}()
+	orderBook10 = make(map[string]OrderBook10)
+	spareBooks = make(map[string]OrderBook10)
+	instruments = make(map[string]Instrument)
+	publicTables = NewTables()
 }
 
-func dispatch(msg []byte) (err error) {
-
-	//log.Debug(string(msg))
-	message := string(msg)
-
-	if message == "pong" {
-		return handlePing(message)
+func (b *bitmex) dispatch(msg []byte) (err error) {
+	// /realtimemd 多路复用 [type, id, topic, payload]
+	if len(msg) > 0 && msg[0] == '[' {
+		return b.dispatchStream(msg)
 	}
-
-	topic := gjson.GetBytes(msg, "table")
-
-	switch topic.String() {
-	case "orderBook10":
-		return handleOrderBook10(msg)
-	case "execution":
-		return handleExecution(msg)
-	case "position":
-		return handlePosition(msg)
-	case "order":
-		return handleOrder(msg)
-	default:
-		return
-	}
-
-	return
+	return b.route("", msg)
 }
 
-// ping
-func handlePing(msg string) (err error) {
-	log.Debug(msg)
-	// 移仓
-	for _, v := range order {
-
-		if v.OrdStatus != "New" {
-			continue
-		}
-
-		if v.Side == "Buy" && v.Price >= orderBook10[v.Symbol].Bids[Conf.Trading.Range-1][0] {
-			continue
-		}
+// route message of stream, private tables go to the account of the stream
+func (b *bitmex) route(stream string, msg []byte) (err error) {
 
-		if v.Side == "Sell" && v.Price <= orderBook10[v.Symbol].Asks[Conf.Trading.Range-1][0] {
-			continue
-		}
-		go func(v Order) {
-			action := "cancel"
-			params := make(map[string]interface{})
-			params["orderID"] = v.OrderID
-			operate <- Operate{
-				action,
-				params,
-			}
-			log.Infof("%s order %s to be canceled, price is %v, qty is %v", v.Side, v.OrderID, v.Price, v.OrderQty)
-		}(v)
-	}
-
-	// 超出最大持仓量
-	for k, v := range position {
-
-		toBuy := true
-		toSell := true
-		for _, vv := range order {
-			if k != vv.Symbol {
-				continue
-			}
-			if vv.Side == "Buy" && vv.Price == orderBook10[vv.Symbol].Bids[0][0] && vv.OrdStatus == "New" {
-				log.Debug(vv)
-				toBuy = false
-				break
-			}
-		}
-
-		for _, vv := range order {
-			if k != vv.Symbol {
-				continue
-			}
-			if vv.Side == "Sell" && vv.Price == orderBook10[vv.Symbol].Asks[0][0] && vv.OrdStatus == "New" {
-				log.Debug(vv)
-				toSell = false
-				break
-			}
+	//log.Debug(string(msg))
+	if string(msg) == "pong" {
+		// 认证和订阅确认前不交易
+		if !b.session.IsReady() {
+			return
 		}
+		return b.handlePing("pong")
+	}
 
-		log.Infof("CurrentQty: %v", v.CurrentQty)
-		if math.Abs(v.CurrentQty) > Conf.Trading.MaxHoldQty {
-			go func() {
-				action := "create"
-				params := make(map[string]interface{})
-				params["symbol"] = k
-				params["orderQty"] = Conf.Trading.UnitQty * 2
-				params["side"] = "Buy"
-				params["price"] = orderBook10[k].Bids[0][0]
-				if v.CurrentQty > 0 {
-					params["side"] = "Sell"
-					params["price"] = orderBook10[k].Asks[0][0]
-				}
-				if (v.CurrentQty > 0 && toSell) || (v.CurrentQty < 0 && toBuy) {
-					operate <- Operate{
-						action,
-						params,
-					}
-					log.Infof("%s order to be created at %v, qty is %v", k, params["price"], params["orderQty"])
-				}
-			}()
+	table := tableOf(msg)
+	if table == nil {
+		if isControl(msg) {
+			return b.handleControl(stream, msg)
 		}
+		return
 	}
 
-	// 填价
-	for _, s := range Conf.Trading.Symbol {
-		toBuy := true
-		toSell := true
-		for _, v := range order {
-			if s != v.Symbol {
-				continue
-			}
-			if v.Side == "Buy" && v.Price == orderBook10[v.Symbol].Bids[0][0] && v.OrdStatus == "New" {
-				log.Debug(v)
-				toBuy = false
-				break
-			}
-		}
+	switch string(table) {
+	case "orderBook10":
+		return b.handleOrderBook10(msg)
+	case "trade":
+		return b.handleTrade(msg)
+	case "quote":
+		return b.handleQuote(msg)
+	case "liquidation":
+		return b.handleLiquidation(msg)
+	case "funding":
+		return b.handleFunding(msg)
+	}
 
-		for _, v := range order {
-			if s != v.Symbol {
-				continue
-			}
-			if v.Side == "Sell" && v.Price == orderBook10[v.Symbol].Asks[0][0] && v.OrdStatus == "New" {
-				log.Debug(v)
-				toSell = false
-				break
-			}
+	// 表消息只在此解码一次 之后按行合并
+	var handler func(*TableMsg) error
+	var partials *partialBuffer
+	switch string(table) {
+	case "instrument":
+		handler, partials = b.handleInstrument, b.partials
+	case "execution", "position", "order", "margin", "wallet":
+		a := accountOf(stream)
+		if a == nil {
+			return
 		}
-
-		if math.Abs(position[s].CurrentQty) > Conf.Trading.MaxHoldQty {
-			log.Info("reach max hold Qty, stop create order")
-			break
+		partials = a.partials
+		switch string(table) {
+		case "execution":
+			handler = bind(a, b.handleExecution)
+		case "position":
+			handler = bind(a, b.handlePosition)
+		case "order":
+			handler = bind(a, b.handleOrder)
+		case "margin":
+			handler = bind(a, b.handleMargin)
+		case "wallet":
+			handler = bind(a, b.handleWallet)
 		}
+	default:
+		return
+	}
+	tm, err := decodeTable(msg)
+	if err != nil {
+		return
+	}
+	return partials.handle(tm, handler)
+}
 
-		log.Infof("toBuy: %v, toSell: %v", toBuy, toSell)
-		go func(toBuy, toSell bool) {
-			action := "create"
-			if toBuy {
-				params := make(map[string]interface{})
-				params["symbol"] = s
-				params["orderQty"] = Conf.Trading.UnitQty
-				params["side"] = "Buy"
-				params["price"] = orderBook10[s].Bids[0][0]
-				operate <- Operate{
-					action,
-					params,
-				}
-				log.Infof("%s order to be create at %v, qty is %v", params["side"], params["price"], params["orderQty"])
-			}
-			if toSell {
-				params2 := make(map[string]interface{})
-				params2["symbol"] = s
-				params2["orderQty"] = Conf.Trading.UnitQty
-				params2["side"] = "Sell"
-				params2["price"] = orderBook10[s].Asks[0][0]
-				operate <- Operate{
-					action,
-					params2,
-				}
-				log.Infof("%s order to be create at %v, qty is %v", params2["side"], params2["price"], params2["orderQty"])
-			}
-		}(toBuy, toSell)
+// bind account table handler
+func bind(a *Account, handler func(*Account, *TableMsg) error) func(*TableMsg) error {
+	return func(tm *TableMsg) error {
+		return handler(a, tm)
 	}
+}
 
+// ping
+func (b *bitmex) handlePing(msg string) (err error) {
+	log.Debug(msg)
+	onPing()
 	return
 }
 
 // 10档报价
-func handleOrderBook10(msg []byte) (err error) {
-	obm := &OrderBook10Msg{}
-	if err = json.Unmarshal(msg, obm); err != nil {
+func (b *bitmex) handleOrderBook10(msg []byte) (err error) {
+	return decodeOrderBook10(msg, func(obm *OrderBook10Msg) {
+		if obm.Action != "partial" && obm.Action != "update" {
+			return
+		}
+		for _, v := range obm.Data {
+			onBook(v)
+		}
+	})
+}
+
+// 成交
+func (b *bitmex) handleTrade(msg []byte) (err error) {
+	tm := &TradeMsg{}
+	if err = json.Unmarshal(msg, tm); err != nil {
 		return
 	}
 
-	if obm.Action == "partial" || obm.Action == "update" {
-		orderBook10 = make(map[string]OrderBook10)
-		for _, order := range obm.Data {
-			orderBook10[order.Symbol] = order
-			log.Info("---")
-			log.Infof("range: %v ~ %v", order.Asks[Conf.Trading.Range-1][0], order.Bids[Conf.Trading.Range-1][0])
-			log.Infof("Asks: %v\t%v\t%v\t%v\t%v", order.Asks[0], order.Asks[1], order.Asks[2], order.Asks[3], order.Asks[4])
-			log.Infof("Bids: %v\t%v\t%v\t%v\t%v", order.Bids[0], order.Bids[1], order.Bids[2], order.Bids[3], order.Bids[4])
-			log.Info("---")
+	if tm.Action == "partial" || tm.Action == "insert" {
+		for _, v := range tm.Data {
+			onTrade(v)
 		}
-		return
 	}
 	return
 }
 
-// 订单成交
-func handleExecution(msg []byte) (err error) {
-	em := &ExecutionMsg{}
-	if err = json.Unmarshal(msg, em); err != nil {
-		log.Info(err)
+// 最优报价
+func (b *bitmex) handleQuote(msg []byte) (err error) {
+	qm := &QuoteMsg{}
+	if err = json.Unmarshal(msg, qm); err != nil {
 		return
 	}
 
-	if em.Action == "insert" {
-		for _, v := range em.Data {
-			if v.OrdStatus == "Filled" {
-				log.Infof("%s order %s filled at %v, qty is %v", v.Side, v.OrderID, v.Price, v.CumQty)
-				params := make(map[string]interface{})
-				params["symbol"] = v.Symbol
-
-				params["side"] = "Buy"
-				if v.Side == "Buy" {
-					params["side"] = "Sell"
-				}
-
-				params["orderQty"] = v.CumQty
-
-				spread := Conf.Trading.Spread
-				if v.Side == "Sell" {
-					spread *= -1
-				}
-				params["price"] = v.Price + spread*Conf.Trading.PriceUint
-
-				if params["side"] == "Buy" && params["price"].(float64) > orderBook10[v.Symbol].Bids[0][0] {
-					params["price"] = orderBook10[v.Symbol].Asks[0][0]
-				}
-
-				if params["side"] == "Sell" && params["price"].(float64) < orderBook10[v.Symbol].Asks[0][0] {
-					params["price"] = orderBook10[v.Symbol].Bids[0][0]
-				}
-
-				operate <- Operate{
-					"create",
-					params,
-				}
-				log.Infof("%s order to be created at %v, qty is %v", params["side"], params["price"], params["orderQty"])
-			}
+	if qm.Action == "partial" || qm.Action == "insert" {
+		for _, v := range qm.Data {
+			onQuote(v)
 		}
 	}
 	return
 }
 
-// 头寸
-func handlePosition(msg []byte) (err error) {
-	pm := &PositionMsg{}
-	if err = json.Unmarshal(msg, pm); err != nil {
-		log.Info(err)
+// 强平 只关注新的强平单
+func (b *bitmex) handleLiquidation(msg []byte) (err error) {
+	lm := &LiquidationMsg{}
+	if err = json.Unmarshal(msg, lm); err != nil {
 		return
 	}
 
-	position = make(map[string]Position)
-	if pm.Action == "partial" {
-		for _, p := range pm.Data {
-			position[p.Symbol] = p
-		}
-		log.Debug("partial position", position["XBTUSD"])
+	if lm.Action != "insert" {
 		return
 	}
-
-	update := func(source, patch Position) Position {
-		s := source
-		v := reflect.ValueOf(s)
-		vv := reflect.ValueOf(patch)
-		v_elem := reflect.ValueOf(&s).Elem()
-
-		for i := 0; i < v.NumField(); i++ {
-			//f := v.Field(i)
-			ff := vv.Field(i)
-			if reflect.DeepEqual(ff.Interface(), reflect.Zero(ff.Type()).Interface()) {
-				continue
-			}
-			v_elem.Field(i).Set(ff)
-		}
-		return s
+	for _, v := range lm.Data {
+		onLiquidation(v)
 	}
+	return
+}
 
-	if pm.Action == "update" {
-		for _, p := range pm.Data {
-			position[p.Symbol] = update(position[p.Symbol], p)
+// 产品 update 只推送变化的字段 按字段合并到表中已有的记录
+func (b *bitmex) handleInstrument(tm *TableMsg) (err error) {
+	// 按 symbol 订阅 partial 只替换自身的记录
+	if tm.Action == "partial" {
+		publicTables.Init("instrument", tm.Keys)
+	}
+	for _, raw := range tm.Data {
+		_, row, err := publicTables.Apply("instrument", tm.Action, raw)
+		if err != nil {
+			return err
 		}
-		log.Debug("update position", position["XBTUSD"])
-		return
+		symbol := gjson.GetBytes(raw, "symbol").String()
+		if tm.Action == "delete" {
+			delete(instruments, symbol)
+			continue
+		}
+		instruments[symbol] = row.(Instrument)
 	}
-
 	return
 }
 
-// 未成交订单
-func handleOrder(msg []byte) (err error) {
-	log.Debug(string(msg))
-	om := &OrderMsg{}
-	if err = json.Unmarshal(msg, om); err != nil {
+// 资金费结算
+func (b *bitmex) handleFunding(msg []byte) (err error) {
+	fm := &FundingMsg{}
+	if err = json.Unmarshal(msg, fm); err != nil {
 		return
 	}
-
-	if om.Action == "partial" {
-		order = om.Data
-		log.Debug(order)
-		log.Debug(len(order))
-		return
+	for _, v := range fm.Data {
+		fundings[v.Symbol] = v
+		if fm.Action == "insert" {
+			log.Infof("%s funding rate %v settled at %s", v.Symbol, v.FundingRate, v.Timestamp)
+		}
 	}
+	return
+}
 
-	if om.Action == "insert" {
-		order = append(order, om.Data...)
-		log.Debug(order)
-		log.Debug(len(order))
+// 订单成交
+func (b *bitmex) handleExecution(a *Account, tm *TableMsg) (err error) {
+	// partial 为历史成交 只记录不处理
+	if tm.Action != "partial" && tm.Action != "insert" {
 		return
 	}
-
-	update := func(source, patch Order) Order {
-		s := source
-		v := reflect.ValueOf(s)
-		vv := reflect.ValueOf(patch)
-		v_elem := reflect.ValueOf(&s).Elem()
-
-		for i := 0; i < v.NumField(); i++ {
-			//f := v.Field(i)
-			ff := vv.Field(i)
-			if reflect.DeepEqual(ff.Interface(), reflect.Zero(ff.Type()).Interface()) {
-				continue
-			}
-			v_elem.Field(i).Set(ff)
+	for _, raw := range tm.Data {
+		e := Execution{}
+		if err = json.Unmarshal(raw, &e); err != nil {
+			log.Info(err)
+			return
 		}
-		return s
+		a.onExecution(e, tm.Action == "partial")
 	}
+	return
+}
 
-	if om.Action == "update" {
-		for _, v := range om.Data {
-			for kk, vv := range order {
-				if v.OrderID == vv.OrderID {
-					order[kk] = update(vv, v)
-				}
-			}
+// 头寸
+func (b *bitmex) handlePosition(a *Account, tm *TableMsg) (err error) {
+	if tm.Action == "partial" {
+		a.tables.Reset("position", tm.Keys)
+		a.position = make(map[string]Position)
+		a.fills.reset("")
+	}
+	for _, raw := range tm.Data {
+		_, row, err := a.tables.Apply("position", tm.Action, raw)
+		if err != nil {
+			return err
 		}
-		for k, v := range order {
-			if v.OrdStatus == "Canceled" {
-				order = append(order[:k], order[k+1:]...)
-			}
+		if tm.Action == "delete" {
+			symbol := gjson.GetBytes(raw, "symbol").String()
+			delete(a.position, symbol)
+			a.fills.reset(symbol)
+			continue
 		}
-		return
+		p := row.(Position)
+		a.setPosition(p)
+		log.Debugf("%s position %s", tm.Action, p.Symbol)
 	}
-
 	return
 }
 
-// 下单
-func createOrder(params map[string]interface{}) error {
-	or := OrderResponse{}
-	ep := Endpoint{
-		"POST",
-		"/order",
-		Conf.RestConfig,
-		Conf.AuthConfig,
-		params,
-		&or,
-		nil,
-	}
-	return ep.Do()
-}
+// 未成交订单
+func (b *bitmex) handleOrder(a *Account, tm *TableMsg) (err error) {
+	if tm.Action == "partial" {
+		a.tables.Reset("order", tm.Keys)
+	}
+	for _, raw := range tm.Data {
+		// 行只在表中解码一次 事件标记取自原始数据
+		marker := orderMarker(gjson.GetBytes(raw, "orderID").String(), raw)
+		if tm.Action == "partial" {
+			a.orderEvents.mark(marker)
+		} else if a.orderEvents.seen(marker) {
+			log.Debugf("order %s %s already processed", marker, tm.Action)
+			continue
+		}
 
-// 改单
-func amendOrder(params map[string]interface{}) error {
-	or := OrderResponse{}
-	ep := Endpoint{
-		"PUT",
-		"/order",
-		Conf.RestConfig,
-		Conf.AuthConfig,
-		params,
-		&or,
-		nil,
-	}
-	return ep.Do()
-}
+		prev, row, err := a.tables.Apply("order", tm.Action, raw)
+		if err != nil {
+			return err
+		}
+		if tm.Action == "partial" || tm.Action == "delete" {
+			continue
+		}
 
-// 取消订单
-func cancelOrder(params map[string]interface{}) error {
-	ep := Endpoint{
-		"DELETE",
-		"/order",
-		Conf.RestConfig,
-		Conf.AuthConfig,
-		params,
-		nil,
-		nil,
-	}
-	return ep.Do()
-}
+		o, from := bitmexOrder(row.(Order)), ""
+		if p, ok := prev.(Order); ok {
+			from = p.OrdStatus
+		}
+		a.onOrder(from, o, tm.Action == "update")
+		if isTerminal(o) {
+			a.tables.Delete("order", raw)
+		}
+	}
 
-// 设置杠杆率
-func setLeverage(params map[string]interface{}) error {
-	ep := Endpoint{
-		"POST",
-		"/position/leverage",
-		Conf.RestConfig,
-		Conf.AuthConfig,
-		params,
-		nil,
-		nil,
-	}
-	return ep.Do()
+	// 重建订单列表 表中已是解码后的订单
+	rows := a.tables.Rows("order")
+	orders := make([]Order, 0, len(rows))
+	for _, row := range rows {
+		orders = append(orders, bitmexOrder(row.(Order)))
+	}
+	a.setOrders(orders)
+	return
 }
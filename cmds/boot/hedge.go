@@ -0,0 +1,228 @@
+package boot
+
+import (
+	log "github.com/sirupsen/logrus"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	hedger *hedgeManager
+)
+
+type (
+	// 已发出但还没在订单表中出现的对冲单
+	pendingHedge struct {
+		Qty  float64 // 带方向 买为正
+		Sent time.Time
+	}
+
+	// 把做市持仓对冲到另一个合约或账户
+	// 敞口 = 做市持仓 * Ratio + 对冲持仓 + 未成交的对冲单 超过 Threshold 时以对手价限价单对冲到零
+	// 敞口持续超过 MaxUnhedged 秒后限价越过对手价 Slippage 个价格单位 MaxUnhedged 为 0 时始终越过
+	hedgeManager struct {
+		sync.Mutex
+		conf    func() *Hedge
+		pending map[string]pendingHedge // clOrdID -> 对冲单
+		since   time.Time               // 敞口出现的时间
+	}
+)
+
+func init() {
+	hedger = newHedgeManager(func() *Hedge { return Conf.Hedge })
+}
+
+func newHedgeManager(conf func() *Hedge) *hedgeManager {
+	return &hedgeManager{conf: conf, pending: make(map[string]pendingHedge)}
+}
+
+// seen hedge order acked or filled, the order table and position count it from now on
+func (h *hedgeManager) seen(clOrdID string) {
+	if purposeOf(clOrdID) != PurposeHedge {
+		return
+	}
+	h.Lock()
+	defer h.Unlock()
+	delete(h.pending, clOrdID)
+}
+
+// hedgeSymbol position of symbol is managed by hedging, not unwound by MaxHoldQty
+func hedgeSymbol(symbol string) bool {
+	return symbol != "" && (symbol == Conf.Hedge.Symbol || symbol == Conf.Basis.Hedge)
+}
+
+// hedgeAccount account holding the hedge, nil if hedging disabled or account not found
+func hedgeAccount() *Account {
+	if Conf.Hedge.Symbol == "" || len(accounts) == 0 {
+		return nil
+	}
+	if Conf.Hedge.Account == "" {
+		return accounts[0]
+	}
+	for _, a := range accounts {
+		if a.Name == Conf.Hedge.Account {
+			return a
+		}
+	}
+	return nil
+}
+
+// quotingQty net position of quoting symbols over all accounts, hedge symbol excluded
+func quotingQty() (qty float64) {
+	for _, a := range accounts {
+		for _, s := range a.trading().Symbol {
+			if s = strings.TrimSpace(s); s == "" || s == Conf.Hedge.Symbol {
+				continue
+			}
+			qty += a.position[s].CurrentQty
+		}
+	}
+	return
+}
+
+// timeout hedge orders not filled in time are canceled and resent
+func (h *hedgeManager) timeout() time.Duration {
+	return time.Duration(h.conf().Timeout) * time.Second
+}
+
+// exposure unhedged delta in hedge contracts of quoting position qty, stale working hedge orders are returned to cancel
+func (h *hedgeManager) exposure(a *Account, qty float64, now time.Time) (delta float64, stale []Order) {
+	h.Lock()
+	defer h.Unlock()
+
+	c := h.conf()
+	delta = qty*c.Ratio + a.position[c.Symbol].CurrentQty
+	for _, o := range a.order {
+		if o.Symbol != c.Symbol || purposeOf(o.ClOrdID) != PurposeHedge {
+			continue
+		}
+		// 已在订单表中出现 以订单表为准
+		delete(h.pending, o.ClOrdID)
+		if !isWorking(o) {
+			continue
+		}
+		if now.Sub(parseTime(o.Timestamp)) > h.timeout() {
+			stale = append(stale, o)
+			continue
+		}
+		if o.Side == "Buy" {
+			delta += o.LeavesQty
+		} else {
+			delta -= o.LeavesQty
+		}
+	}
+	for id, p := range h.pending {
+		if now.Sub(p.Sent) > h.timeout() {
+			log.Infof("hedge order %s not acked in %v, given up", id, h.timeout())
+			delete(h.pending, id)
+			continue
+		}
+		delta += p.Qty
+	}
+
+	if delta == 0 || math.Abs(delta) < c.Threshold {
+		h.since = time.Time{}
+	} else if h.since.IsZero() {
+		h.since = now
+	}
+	return
+}
+
+// overdue exposure lasted longer than MaxUnhedged
+func (h *hedgeManager) overdue(now time.Time) bool {
+	h.Lock()
+	defer h.Unlock()
+	max := h.conf().MaxUnhedged
+	return max > 0 && !h.since.IsZero() && now.Sub(h.since) > time.Duration(max)*time.Second
+}
+
+// op aggressive limit order reducing delta, up to Slippage through the book if overdue, false if below threshold or no book
+func (h *hedgeManager) op(delta float64, overdue bool) (Operate, bool) {
+	c := h.conf()
+	symbol := c.Symbol
+	if delta == 0 || math.Abs(delta) < c.Threshold {
+		return Operate{}, false
+	}
+
+	qty := math.Abs(delta)
+	if lot := instruments[symbol].LotSize; lot > 0 {
+		qty = math.Floor(qty/lot) * lot
+	}
+	if c.MaxQty > 0 {
+		qty = math.Min(qty, c.MaxQty)
+	}
+	if qty <= 0 {
+		return Operate{}, false
+	}
+
+	book := orderBook10[symbol]
+	if len(book.Bids) == 0 || len(book.Asks) == 0 {
+		log.Infof("no book of hedge symbol %s", symbol)
+		return Operate{}, false
+	}
+	tick := instruments[symbol].TickSize
+	if tick <= 0 {
+		tick = Conf.Trading.PriceUint
+	}
+	slippage := 0.0
+	if overdue || c.MaxUnhedged <= 0 {
+		slippage = float64(c.Slippage) * tick
+	}
+
+	// 净多头卖出对冲 净空头买入对冲 以对手价加滑点为限价
+	q := Quote{"Sell", book.Bids[0][0] - slippage, qty}
+	if delta < 0 {
+		q = Quote{"Buy", book.Asks[0][0] + slippage, qty}
+	}
+	return createOp(symbol, q, PurposeHedge), true
+}
+
+// hedge hedge net delta of quoting accounts
+func hedge(now time.Time) {
+	a := hedgeAccount()
+	if a == nil {
+		if Conf.Hedge.Symbol != "" {
+			log.Infof("hedge account %s not found", Conf.Hedge.Account)
+		}
+		return
+	}
+	hedger.run(a, quotingQty(), now)
+}
+
+// run hedge quoting position qty on account a
+func (h *hedgeManager) run(a *Account, qty float64, now time.Time) {
+	delta, stale := h.exposure(a, qty, now)
+	if len(stale) > 0 {
+		// 先撤掉未成交的对冲单 下次按最新盘口重下
+		for _, o := range stale {
+			a.submit(Operate{"cancel", o.Symbol, map[string]interface{}{"orderID": o.OrderID}})
+			log.Infof("hedge order %s not filled in %v, to be canceled", o.OrderID, h.timeout())
+		}
+		return
+	}
+	log.Infof("hedge delta of %s: %v", h.conf().Symbol, delta)
+
+	overdue := h.overdue(now)
+	if overdue {
+		alert("%s unhedged %v for more than %ds, hedge %d ticks through the book", h.conf().Symbol, delta, h.conf().MaxUnhedged, h.conf().Slippage)
+	}
+	op, ok := h.op(delta, overdue)
+	if !ok {
+		return
+	}
+
+	qty, _ = op.Params["orderQty"].(float64)
+	if op.Params["side"] == "Sell" {
+		qty = -qty
+	}
+	// 入队后才计入敞口 被丢弃的对冲单下次重新计算
+	if !a.submit(op) {
+		return
+	}
+	h.Lock()
+	h.pending[op.Params["clOrdID"].(string)] = pendingHedge{qty, now}
+	h.Unlock()
+	log.Infof("%s hedge order to be created: %v", a.Name, op.Params)
+}
@@ -0,0 +1,94 @@
+package boot
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestHedge(t *testing.T) {
+	saved := accounts
+	defer func() {
+		accounts = saved
+		Conf = Default()
+		hedger = newHedgeManager(func() *Hedge { return Conf.Hedge })
+		orderBook10 = make(map[string]OrderBook10)
+	}()
+	Conf.Hedge.Symbol = "XBTM19"
+	Conf.Hedge.Account = "hedge"
+	hedger = newHedgeManager(func() *Hedge { return Conf.Hedge })
+
+	main := NewAccount(&AccountConfig{Name: "main"})
+	h := newAccount(&AccountConfig{Name: "hedge", Trading: &Trading{}})
+	accounts = []*Account{main, h}
+	assert.Equal(t, h, hedgeAccount())
+	assert.Equal(t, []string{"XBTUSD", "XBTM19"}, symbols())
+
+	now := time.Now()
+	main.position["XBTUSD"] = Position{Symbol: "XBTUSD", CurrentQty: 500}
+
+	// 没有对冲合约盘口
+	delta, _ := hedger.exposure(h, quotingQty(), now)
+	assert.Equal(t, 500.0, delta)
+	_, ok := hedger.op(delta, false)
+	assert.False(t, ok)
+
+	orderBook10["XBTM19"] = OrderBook10{Symbol: "XBTM19", Bids: []Bid{{6600, 100}}, Asks: []Ask{{6601, 100}}}
+	op, ok := hedger.op(delta, false)
+	assert.True(t, ok)
+	assert.Equal(t, "Sell", op.Params["side"])
+	assert.Equal(t, 500.0, op.Params["orderQty"])
+	assert.Equal(t, 6599.0, op.Params["price"])
+	assert.Equal(t, PurposeHedge, purposeOf(op.Params["clOrdID"].(string)))
+
+	// 已发出未确认的对冲单计入敞口
+	clOrdID := op.Params["clOrdID"].(string)
+	hedger.pending[clOrdID] = pendingHedge{-500, now}
+	delta, _ = hedger.exposure(h, quotingQty(), now)
+	assert.Equal(t, 0.0, delta)
+
+	// 确认后以订单表为准 超时未成交的返回撤单
+	hedger.seen(clOrdID)
+	h.order = []Order{{OrderID: "1", ClOrdID: clOrdID, Symbol: "XBTM19", Side: "Sell", OrdStatus: "New", LeavesQty: 500, Timestamp: now.Format(time.RFC3339Nano)}}
+	delta, stale := hedger.exposure(h, quotingQty(), now)
+	assert.Equal(t, 0.0, delta)
+	assert.Empty(t, stale)
+	delta, stale = hedger.exposure(h, quotingQty(), now.Add(time.Minute))
+	assert.Equal(t, 500.0, delta)
+	assert.Len(t, stale, 1)
+
+	// 成交后敞口在阈值以下
+	h.order = nil
+	h.position["XBTM19"] = Position{Symbol: "XBTM19", CurrentQty: -450}
+	delta, _ = hedger.exposure(h, quotingQty(), now)
+	assert.Equal(t, 50.0, delta)
+	_, ok = hedger.op(delta, false)
+	assert.False(t, ok)
+
+	// 单笔上限
+	Conf.Hedge.MaxQty = 200
+	op, ok = hedger.op(-1000, false)
+	assert.True(t, ok)
+	assert.Equal(t, "Buy", op.Params["side"])
+	assert.Equal(t, 200.0, op.Params["orderQty"])
+	assert.Equal(t, 6602.0, op.Params["price"])
+
+	// 设置 MaxUnhedged 后先以对手价对冲 超时后越过 Slippage 仍为限价单
+	Conf.Hedge.MaxUnhedged = 30
+	op, ok = hedger.op(-1000, false)
+	assert.True(t, ok)
+	assert.Equal(t, 6601.0, op.Params["price"])
+	op, ok = hedger.op(-1000, true)
+	assert.True(t, ok)
+	assert.Equal(t, 6602.0, op.Params["price"])
+	assert.Nil(t, op.Params["ordType"])
+
+	// 被丢弃的对冲单不计入敞口
+	h.position["XBTM19"] = Position{Symbol: "XBTM19"}
+	h.Halt("test")
+	hedger.run(h, quotingQty(), now)
+	assert.Empty(t, hedger.pending)
+	h.Resume()
+	hedger.run(h, quotingQty(), now)
+	assert.Len(t, hedger.pending, 1)
+}
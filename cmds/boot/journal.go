@@ -0,0 +1,210 @@
+package boot
+
+import (
+	"database/sql"
+	_ "github.com/mattn/go-sqlite3"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+var (
+	journal *Journal
+)
+
+const journalSchema = `
+CREATE TABLE IF NOT EXISTS executions (
+	exec_id            TEXT PRIMARY KEY,
+	account            TEXT,
+	order_id           TEXT,
+	cl_ord_id          TEXT,
+	symbol             TEXT,
+	side               TEXT,
+	exec_type          TEXT,
+	ord_type           TEXT,
+	ord_status         TEXT,
+	last_qty           REAL,
+	last_px            REAL,
+	exec_comm          REAL,
+	commission         REAL,
+	last_liquidity_ind TEXT,
+	currency           TEXT,
+	settl_currency     TEXT,
+	text               TEXT,
+	transact_time      TEXT,
+	timestamp          TEXT,
+	recorded_at        TEXT
+);
+CREATE INDEX IF NOT EXISTS executions_symbol ON executions (symbol, transact_time);
+CREATE TABLE IF NOT EXISTS order_events (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	account     TEXT,
+	order_id    TEXT,
+	cl_ord_id   TEXT,
+	symbol      TEXT,
+	side        TEXT,
+	ord_type    TEXT,
+	price       REAL,
+	order_qty   REAL,
+	leaves_qty  REAL,
+	cum_qty     REAL,
+	avg_px      REAL,
+	from_status TEXT,
+	to_status   TEXT,
+	text        TEXT,
+	timestamp   TEXT,
+	recorded_at TEXT
+);
+CREATE INDEX IF NOT EXISTS order_events_order ON order_events (order_id);
+CREATE TABLE IF NOT EXISTS contracts (
+	symbol     TEXT PRIMARY KEY,
+	is_inverse INTEGER,
+	multiplier REAL
+);
+`
+
+type (
+	// 成交和订单状态变化记录 保存在 sqlite
+	Journal struct {
+		db *sql.DB
+	}
+)
+
+// OpenJournal open or create journal database
+func OpenJournal(path string) (j *Journal, err error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return
+	}
+	if _, err = db.Exec(journalSchema); err != nil {
+		db.Close()
+		return
+	}
+	return &Journal{db}, nil
+}
+
+func (j *Journal) Close() error {
+	return j.db.Close()
+}
+
+// RecordExecution insert execution of account, executions already recorded are ignored
+func (j *Journal) RecordExecution(account string, e Execution) (err error) {
+	_, err = j.db.Exec(`INSERT OR IGNORE INTO executions (
+		exec_id, account, order_id, cl_ord_id, symbol, side, exec_type, ord_type, ord_status,
+		last_qty, last_px, exec_comm, commission, last_liquidity_ind, currency, settl_currency,
+		text, transact_time, timestamp, recorded_at
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.ExecID, account, e.OrderID, e.ClOrdID, e.Symbol, e.Side, e.ExecType, e.OrdType, e.OrdStatus,
+		e.LastQty, e.LastPx, e.ExecComm, e.Commission, e.LastLiquidityInd, e.Currency, e.SettlCurrency,
+		e.Text, e.TransactTime, e.Timestamp, time.Now().UTC().Format(time.RFC3339Nano),
+	)
+	return
+}
+
+// RecordOrder insert order state transition of account
+func (j *Journal) RecordOrder(account, from string, o Order) (err error) {
+	_, err = j.db.Exec(`INSERT INTO order_events (
+		account, order_id, cl_ord_id, symbol, side, ord_type, price, order_qty, leaves_qty, cum_qty, avg_px,
+		from_status, to_status, text, timestamp, recorded_at
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		account, o.OrderID, o.ClOrdID, o.Symbol, o.Side, o.OrdType, o.Price, o.OrderQty, o.LeavesQty, o.CumQty, o.AvgPx,
+		from, o.OrdStatus, o.Text, o.Timestamp, time.Now().UTC().Format(time.RFC3339Nano),
+	)
+	return
+}
+
+// RecordContract save contract spec of instrument for pnl
+func (j *Journal) RecordContract(i Instrument) (err error) {
+	_, err = j.db.Exec(`INSERT OR REPLACE INTO contracts (symbol, is_inverse, multiplier) VALUES (?, ?, ?)`,
+		i.Symbol, i.IsInverse, i.Multiplier)
+	return
+}
+
+// Contracts recorded contract specs
+func (j *Journal) Contracts() (contracts []Instrument, err error) {
+	rows, err := j.db.Query(`SELECT symbol, is_inverse, multiplier FROM contracts ORDER BY symbol`)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		i := Instrument{}
+		if err = rows.Scan(&i.Symbol, &i.IsInverse, &i.Multiplier); err != nil {
+			return
+		}
+		contracts = append(contracts, i)
+	}
+	err = rows.Err()
+	return
+}
+
+// Accounts accounts having executions, empty for records before accounts were journaled
+func (j *Journal) Accounts() (accounts []string, err error) {
+	rows, err := j.db.Query(`SELECT DISTINCT account FROM executions ORDER BY 1`)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var account string
+		if err = rows.Scan(&account); err != nil {
+			return
+		}
+		accounts = append(accounts, account)
+	}
+	err = rows.Err()
+	return
+}
+
+// Executions executions of account and symbol ordered by transact time, all symbols if empty
+func (j *Journal) Executions(account, symbol string) (executions []Execution, err error) {
+	rows, err := j.db.Query(`SELECT
+		exec_id, order_id, cl_ord_id, symbol, side, exec_type, ord_type, ord_status,
+		last_qty, last_px, exec_comm, commission, last_liquidity_ind, currency, settl_currency,
+		text, transact_time, timestamp
+	FROM executions WHERE account = ? AND (? = '' OR symbol = ?) ORDER BY transact_time, rowid`, account, symbol, symbol)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		e := Execution{}
+		if err = rows.Scan(
+			&e.ExecID, &e.OrderID, &e.ClOrdID, &e.Symbol, &e.Side, &e.ExecType, &e.OrdType, &e.OrdStatus,
+			&e.LastQty, &e.LastPx, &e.ExecComm, &e.Commission, &e.LastLiquidityInd, &e.Currency, &e.SettlCurrency,
+			&e.Text, &e.TransactTime, &e.Timestamp,
+		); err != nil {
+			return
+		}
+		executions = append(executions, e)
+	}
+	err = rows.Err()
+	return
+}
+
+// journalExecution record execution of account if journal is enabled
+func journalExecution(account string, e Execution) {
+	if journal == nil {
+		return
+	}
+	if err := journal.RecordExecution(account, e); err != nil {
+		log.Error(err)
+	}
+	if i, ok := instruments[e.Symbol]; ok && i.Multiplier != 0 {
+		if err := journal.RecordContract(i); err != nil {
+			log.Error(err)
+		}
+	}
+}
+
+// journalOrder record order state transition of account if journal is enabled
+func journalOrder(account, from string, o Order) {
+	if journal == nil || from == o.OrdStatus {
+		return
+	}
+	if err := journal.RecordOrder(account, from, o); err != nil {
+		log.Error(err)
+	}
+}
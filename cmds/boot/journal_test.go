@@ -0,0 +1,70 @@
+package boot
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "marketboy")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	j, err := OpenJournal(filepath.Join(dir, "journal.db"))
+	assert.Nil(t, err)
+	defer j.Close()
+
+	e := Execution{
+		ExecID:           "e1",
+		OrderID:          "o1",
+		Symbol:           "XBTUSD",
+		Side:             "Buy",
+		ExecType:         "Trade",
+		LastQty:          100,
+		LastPx:           6500,
+		ExecComm:         -385,
+		LastLiquidityInd: "AddedLiquidity",
+		TransactTime:     "2018-10-01T00:00:00.000Z",
+	}
+	assert.Nil(t, j.RecordExecution("main", e))
+	assert.Nil(t, j.RecordExecution("main", e))
+	e2 := e
+	e2.ExecID, e2.Symbol, e2.TransactTime = "e2", "ETHUSD", "2018-10-01T00:00:01.000Z"
+	assert.Nil(t, j.RecordExecution("main", e2))
+
+	e3 := e
+	e3.ExecID = "e3"
+	assert.Nil(t, j.RecordExecution("hedge", e3))
+
+	all, err := j.Executions("main", "")
+	assert.Nil(t, err)
+	assert.Len(t, all, 2)
+
+	xbt, err := j.Executions("main", "XBTUSD")
+	assert.Nil(t, err)
+	assert.Equal(t, []Execution{e}, xbt)
+
+	// 按账户区分
+	accounts, err := j.Accounts()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"hedge", "main"}, accounts)
+	hedge, err := j.Executions("hedge", "")
+	assert.Nil(t, err)
+	assert.Equal(t, []Execution{e3}, hedge)
+
+	assert.Nil(t, j.RecordOrder("main", "", Order{OrderID: "o1", OrdStatus: "New"}))
+	assert.Nil(t, j.RecordOrder("main", "New", Order{OrderID: "o1", OrdStatus: "Filled"}))
+	var n int
+	assert.Nil(t, j.db.QueryRow("SELECT COUNT(*) FROM order_events WHERE order_id = 'o1' AND account = 'main'").Scan(&n))
+	assert.Equal(t, 2, n)
+
+	assert.Nil(t, j.RecordContract(Instrument{Symbol: "ETHUSD", Multiplier: 100}))
+	assert.Nil(t, j.RecordContract(Instrument{Symbol: "XBTUSD", Multiplier: -100000000, IsInverse: true}))
+	assert.Nil(t, j.RecordContract(Instrument{Symbol: "ETHUSD", Multiplier: 100}))
+	contracts, err := j.Contracts()
+	assert.Nil(t, err)
+	assert.Equal(t, []Instrument{{Symbol: "ETHUSD", Multiplier: 100}, {Symbol: "XBTUSD", Multiplier: -100000000, IsInverse: true}}, contracts)
+}
@@ -0,0 +1,54 @@
+package boot
+
+import (
+	"math"
+)
+
+type (
+	// 多档报价 从 touch 起在 Range 档内各挂 Levels 个买单和卖单
+	ladderStrategy struct{}
+)
+
+// ladderQty size of level i by Quoting.Progression
+func ladderQty(i int64, unit float64) float64 {
+	switch Conf.Quoting.Progression {
+	case "linear":
+		return math.Round(unit * (1 + float64(i)*Conf.Quoting.SizeStep))
+	case "geometric":
+		return math.Round(unit * math.Pow(Conf.Quoting.SizeStep, float64(i)))
+	default:
+		return unit
+	}
+}
+
+func (ladderStrategy) Quotes(snap Snapshot) (quotes []Quote) {
+	book := snap.Book
+	bidTop, askTop, ok := touch(snap)
+	if !ok {
+		return
+	}
+	t := snap.trading()
+	tick := t.PriceUint
+
+	// 不超出 Range 档 避免被移仓撤单
+	bidFloor := book.Bids[len(book.Bids)-1][0]
+	if int64(len(book.Bids)) >= t.Range {
+		bidFloor = book.Bids[t.Range-1][0]
+	}
+	askCeil := book.Asks[len(book.Asks)-1][0]
+	if int64(len(book.Asks)) >= t.Range {
+		askCeil = book.Asks[t.Range-1][0]
+	}
+
+	for i := int64(0); i < Conf.Quoting.Levels; i++ {
+		offset := float64(Conf.Quoting.Offset+i*Conf.Quoting.Step) * tick
+		qty := ladderQty(i, t.UnitQty)
+		if bid := bidTop - offset; bid >= bidFloor {
+			quotes = append(quotes, Quote{"Buy", bid, qty})
+		}
+		if ask := askTop + offset; ask <= askCeil {
+			quotes = append(quotes, Quote{"Sell", ask, qty})
+		}
+	}
+	return
+}
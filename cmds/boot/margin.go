@@ -0,0 +1,146 @@
+package boot
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"math"
+)
+
+type (
+	// 保证金 金额以 satoshi 计
+	Margin struct {
+		Account         float64 `json:"account"`
+		Currency        string  `json:"currency"`
+		WalletBalance   float64 `json:"walletBalance"`   // 钱包余额
+		MarginBalance   float64 `json:"marginBalance"`   // 保证金余额 钱包余额 + 未实现盈亏
+		AvailableMargin float64 `json:"availableMargin"` // 可用保证金
+		InitMargin      float64 `json:"initMargin"`      // 委托占用的保证金
+		MaintMargin     float64 `json:"maintMargin"`     // 持仓占用的保证金
+		MarginUsedPcnt  float64 `json:"marginUsedPcnt"`  // 保证金使用率
+		MarginLeverage  float64 `json:"marginLeverage"`
+		UnrealisedPnl   float64 `json:"unrealisedPnl"`
+		Timestamp       string  `json:"timestamp"`
+	}
+
+	// 钱包 金额以 satoshi 计
+	Wallet struct {
+		Account     float64 `json:"account"`
+		Currency    string  `json:"currency"`
+		Amount      float64 `json:"amount"`
+		PrevAmount  float64 `json:"prevAmount"`
+		DeltaAmount float64 `json:"deltaAmount"`
+		Timestamp   string  `json:"timestamp"`
+	}
+)
+
+// 保证金 update 只推送变化的字段 按字段合并到表中已有的记录
+func (b *bitmex) handleMargin(a *Account, tm *TableMsg) (err error) {
+	a.marginMu.Lock()
+	defer a.marginMu.Unlock()
+	if tm.Action == "partial" {
+		a.tables.Reset("margin", tm.Keys)
+		a.margins = make(map[string]Margin)
+	}
+	for _, raw := range tm.Data {
+		_, row, err := a.tables.Apply("margin", tm.Action, raw)
+		if err != nil {
+			return err
+		}
+		if tm.Action == "delete" {
+			delete(a.margins, gjson.GetBytes(raw, "currency").String())
+			continue
+		}
+		m := row.(Margin)
+		a.margins[m.Currency] = m
+		log.Debugf("%s available margin %v, wallet balance %v, margin used %.2f%%", m.Currency, m.AvailableMargin, m.WalletBalance, m.MarginUsedPcnt*100)
+	}
+	return
+}
+
+// 钱包 update 只推送变化的字段 按字段合并到表中已有的记录
+func (b *bitmex) handleWallet(a *Account, tm *TableMsg) (err error) {
+	a.marginMu.Lock()
+	defer a.marginMu.Unlock()
+	if tm.Action == "partial" {
+		a.tables.Reset("wallet", tm.Keys)
+		a.wallets = make(map[string]Wallet)
+	}
+	for _, raw := range tm.Data {
+		_, row, err := a.tables.Apply("wallet", tm.Action, raw)
+		if err != nil {
+			return err
+		}
+		if tm.Action == "delete" {
+			delete(a.wallets, gjson.GetBytes(raw, "currency").String())
+			continue
+		}
+		w := row.(Wallet)
+		a.wallets[w.Currency] = w
+	}
+	return
+}
+
+// AvailableMargin available margin of currency in satoshi, false if margin not received yet
+func (a *Account) AvailableMargin(currency string) (float64, bool) {
+	a.marginMu.Lock()
+	defer a.marginMu.Unlock()
+	m, ok := a.margins[currency]
+	return m.AvailableMargin, ok
+}
+
+// isReduceOnly order can only reduce position, no margin required
+func isReduceOnly(params map[string]interface{}) bool {
+	flags := paramFlags(params)
+	return hasFlag(flags, FlagReduceOnly) || hasFlag(flags, FlagClose)
+}
+
+// orderMargin initial margin of order in satoshi
+func (a *Account) orderMargin(symbol string, qty, price float64) float64 {
+	i := instruments[symbol]
+
+	// 合约价值
+	value := 0.0
+	switch {
+	case i.Multiplier == 0 || i.IsInverse:
+		if price <= 0 {
+			return 0
+		}
+		value = qty / price * Satoshi
+	default:
+		value = qty * price * math.Abs(i.Multiplier)
+	}
+
+	// 初始保证金率 逐仓按杠杆 全仓按合约最低要求
+	rate := i.InitMargin
+	if leverage := a.position[symbol].Leverage; leverage > 0 && !a.position[symbol].CrossMargin {
+		rate = math.Max(rate, 1/leverage)
+	} else if leverage := a.trading().Leverage; leverage > 0 {
+		rate = math.Max(rate, 1/leverage)
+	}
+	if rate <= 0 {
+		rate = 1
+	}
+	return value * rate
+}
+
+// checkMargin reject new order if its initial margin exceeds MarginConfig.MaxUsage of available margin
+func (a *Account) checkMargin(op Operate) error {
+	if op.Action != "create" || Conf.MarginConfig.MaxUsage <= 0 || isReduceOnly(op.Params) {
+		return nil
+	}
+
+	available, ok := a.AvailableMargin(Conf.MarginConfig.Currency)
+	if !ok {
+		// 还没有收到保证金数据 交给交易所判断
+		return nil
+	}
+
+	qty, _ := op.Params["orderQty"].(float64)
+	price, _ := op.Params["price"].(float64)
+	required := a.orderMargin(op.Symbol, qty, price)
+	if required > available*Conf.MarginConfig.MaxUsage {
+		return fmt.Errorf("initial margin %.0f exceeds %.0f%% of available margin %.0f", required, Conf.MarginConfig.MaxUsage*100, available)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package boot
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCheckMargin(t *testing.T) {
+	bm := newBitmex().(*bitmex)
+	defer func() {
+		Conf = Default()
+		instruments = make(map[string]Instrument)
+	}()
+	instruments = map[string]Instrument{"XBTUSD": {Symbol: "XBTUSD", Multiplier: -100000000, IsInverse: true, InitMargin: 0.01}}
+	a := newAccount(&AccountConfig{Name: "test"})
+	a.position = map[string]Position{"XBTUSD": {Symbol: "XBTUSD", Leverage: 10}}
+
+	// 1000 contracts at 5000 is 0.2 XBT, 10x leverage needs 0.02 XBT
+	assert.InDelta(t, 2000000.0, a.orderMargin("XBTUSD", 1000, 5000), 1e-6)
+
+	create := func(flags ...string) Operate {
+		params := map[string]interface{}{"symbol": "XBTUSD", "side": "Buy", "orderQty": 1000.0, "price": 5000.0}
+		if len(flags) > 0 {
+			params["flags"] = flags
+		}
+		return Operate{"create", "XBTUSD", params}
+	}
+
+	// no margin data yet
+	assert.Nil(t, a.checkMargin(create()))
+
+	assert.Nil(t, bm.handleMargin(a, tableMsg(`{"table":"margin","action":"partial","data":[{"currency":"XBt","availableMargin":2000000,"walletBalance":3000000}]}`)))
+	assert.NotNil(t, a.checkMargin(create()))
+	assert.Nil(t, a.checkMargin(create(FlagPostOnly, FlagReduceOnly)))
+
+	// 减仓单按用途设置标记 不检查保证金
+	unwind := create()
+	unwind.Params["clOrdID"] = newClOrdID(PurposeUnwind)
+	assert.True(t, a.submit(unwind))
+	assert.Equal(t, []string{FlagReduceOnly}, unwind.Params["flags"])
+	<-a.operate
+
+	// 保证金不足的下单在入队前丢弃 撤单不检查
+	assert.False(t, a.submit(create()))
+	assert.True(t, a.submit(Operate{"cancel", "XBTUSD", map[string]interface{}{"orderID": "1"}}))
+	assert.Len(t, a.operate, 1)
+
+	// update only carries changed fields
+	assert.Nil(t, bm.handleMargin(a, tableMsg(`{"table":"margin","action":"update","data":[{"currency":"XBt","availableMargin":3000000}]}`)))
+	assert.Equal(t, 3000000.0, a.margins["XBt"].WalletBalance)
+	assert.Nil(t, a.checkMargin(create()))
+}
@@ -0,0 +1,355 @@
+package boot
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+type (
+	// 内存交易所 不连接网络 用于测试和空跑
+	// 下单立即确认 限价单在盘口或成交价穿过挂单价时按挂单价全部成交 止损单只挂不触发
+	// 事件按发生顺序排队 在 Serve 或 Drain 中执行 与 websocket 读循环一样串行
+	MockExchange struct {
+		sync.Mutex
+		accounts  []*Account
+		symbols   []string
+		orders    map[string][]Order             // 账户名 -> 未成交订单
+		positions map[string]map[string]Position // 账户名 -> symbol -> 持仓
+		books     map[string]OrderBook10
+		seq       int64
+		events    []func()
+		notify    chan struct{}
+	}
+)
+
+func NewMockExchange() *MockExchange {
+	return &MockExchange{
+		orders:    make(map[string][]Order),
+		positions: make(map[string]map[string]Position),
+		books:     make(map[string]OrderBook10),
+		notify:    make(chan struct{}, 1),
+	}
+}
+
+func (m *MockExchange) Name() string {
+	return "mock"
+}
+
+func (m *MockExchange) Connect(accounts []*Account) error {
+	m.Lock()
+	defer m.Unlock()
+	m.accounts = accounts
+	return nil
+}
+
+func (m *MockExchange) Subscribe(symbols []string) error {
+	m.Lock()
+	defer m.Unlock()
+	m.symbols = symbols
+	return nil
+}
+
+// Serve run queued events and account tasks every Watch seconds until stop
+func (m *MockExchange) Serve(stop <-chan struct{}) error {
+	ticker := time.NewTicker(time.Second * time.Duration(Conf.Trading.Watch))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-m.notify:
+			m.Drain()
+		case <-ticker.C:
+			m.Drain()
+			onPing()
+		}
+	}
+}
+
+// Drain run queued events, for tests not running Serve
+func (m *MockExchange) Drain() {
+	for {
+		m.Lock()
+		events := m.events
+		m.events = nil
+		m.Unlock()
+		if len(events) == 0 {
+			return
+		}
+		for _, fn := range events {
+			fn()
+		}
+	}
+}
+
+// emit queue event, caller holds lock
+func (m *MockExchange) emit(fn func()) {
+	m.events = append(m.events, fn)
+	select {
+	case m.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (m *MockExchange) nextID(prefix string) string {
+	m.seq++
+	return fmt.Sprintf("%s-%d", prefix, m.seq)
+}
+
+// Book publish order book and fill crossed orders
+func (m *MockExchange) Book(book OrderBook10) {
+	m.Lock()
+	defer m.Unlock()
+	m.books[book.Symbol] = book
+	m.emit(func() { onBook(book) })
+	if len(book.Bids) == 0 || len(book.Asks) == 0 {
+		return
+	}
+	m.match(book.Symbol, func(o Order) bool {
+		return (o.Side == "Buy" && o.Price >= book.Asks[0][0]) || (o.Side == "Sell" && o.Price <= book.Bids[0][0])
+	})
+}
+
+// Trade publish public trade and fill orders it trades through
+func (m *MockExchange) Trade(trade Trade) {
+	m.Lock()
+	defer m.Unlock()
+	m.emit(func() { onTrade(trade) })
+	m.match(trade.Symbol, func(o Order) bool {
+		return (o.Side == "Buy" && trade.Price <= o.Price) || (o.Side == "Sell" && trade.Price >= o.Price)
+	})
+}
+
+// match fill working limit orders of symbol, caller holds lock
+func (m *MockExchange) match(symbol string, crossed func(Order) bool) {
+	for _, a := range m.accounts {
+		var working []Order
+		filled := false
+		for _, o := range m.orders[a.Name] {
+			if o.Symbol != symbol || o.OrdType != OrdLimit || !crossed(o) {
+				working = append(working, o)
+				continue
+			}
+			m.fill(a, o)
+			filled = true
+		}
+		if filled {
+			m.orders[a.Name] = working
+			m.emitOrders(a)
+		}
+	}
+}
+
+// fill fill whole order at its price, caller holds lock
+func (m *MockExchange) fill(a *Account, o Order) {
+	qty := o.LeavesQty
+	o.CumQty += qty
+	o.LeavesQty = 0
+	o.AvgPx = o.Price
+	o.OrdStatus = "Filled"
+	o.Timestamp = time.Now().UTC().Format(time.RFC3339Nano)
+
+	currency, settl := "USD", "XBt"
+	if i, ok := instruments[o.Symbol]; ok && !i.IsInverse {
+		currency, settl = i.QuoteCurrency, i.SettlCurrency
+	}
+	e := Execution{
+		ExecID:        m.nextID("exec"),
+		OrderID:       o.OrderID,
+		ClOrdID:       o.ClOrdID,
+		Symbol:        o.Symbol,
+		Side:          o.Side,
+		LastQty:       qty,
+		LastPx:        o.Price,
+		OrderQty:      o.OrderQty,
+		Price:         o.Price,
+		Currency:      currency,
+		SettlCurrency: settl,
+		ExecType:      "Trade",
+		OrdType:       o.OrdType,
+		OrdStatus:     o.OrdStatus,
+		LeavesQty:     0,
+		CumQty:        o.CumQty,
+		AvgPx:         o.AvgPx,
+		Timestamp:     o.Timestamp,
+	}
+
+	positions := m.positions[a.Name]
+	if positions == nil {
+		positions = make(map[string]Position)
+		m.positions[a.Name] = positions
+	}
+	p := positions[o.Symbol]
+	p.Symbol = o.Symbol
+	if o.Side == "Buy" {
+		p.CurrentQty += qty
+	} else {
+		p.CurrentQty -= qty
+	}
+	p.Timestamp = o.Timestamp
+	positions[o.Symbol] = p
+
+	m.emit(func() {
+		a.onExecution(e, false)
+		a.onOrder("New", o, false)
+		a.setPosition(p)
+	})
+}
+
+// emitOrders publish working orders of account, caller holds lock
+func (m *MockExchange) emitOrders(a *Account) {
+	orders := append([]Order(nil), m.orders[a.Name]...)
+	m.emit(func() { a.setOrders(orders) })
+}
+
+// find index of order by orderID or clOrdID, caller holds lock
+func (m *MockExchange) find(a *Account, params map[string]interface{}) (int, error) {
+	orderID, _ := params["orderID"].(string)
+	clOrdID, _ := params["clOrdID"].(string)
+	if v, ok := params["origClOrdID"].(string); ok {
+		clOrdID = v
+	}
+	for i, o := range m.orders[a.Name] {
+		if (orderID != "" && o.OrderID == orderID) || (clOrdID != "" && o.ClOrdID == clOrdID) {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("order not found: %v", params)
+}
+
+func (m *MockExchange) PlaceOrder(a *Account, params map[string]interface{}) error {
+	m.Lock()
+	defer m.Unlock()
+
+	o := Order{
+		OrderID:   m.nextID("order"),
+		OrdType:   OrdLimit,
+		OrdStatus: "New",
+	}
+	o.Symbol, _ = params["symbol"].(string)
+	o.Side, _ = params["side"].(string)
+	o.OrderQty, _ = params["orderQty"].(float64)
+	o.Price, _ = params["price"].(float64)
+	o.StopPx, _ = params["stopPx"].(float64)
+	o.ClOrdID, _ = params["clOrdID"].(string)
+	o.Flags = paramFlags(params)
+	if v, ok := params["ordType"].(string); ok {
+		o.OrdType = v
+	}
+	if o.Symbol == "" || (o.Side != "Buy" && o.Side != "Sell") || o.OrderQty <= 0 {
+		return fmt.Errorf("invalid order: %v", params)
+	}
+	if o.OrdType == OrdLimit && o.Price <= 0 {
+		return fmt.Errorf("invalid price: %v", params)
+	}
+	o.LeavesQty = o.OrderQty
+	o.WorkingIndicator = true
+	o.Timestamp = time.Now().UTC().Format(time.RFC3339Nano)
+
+	// 被动单穿价即撤
+	book := m.books[o.Symbol]
+	crossed := o.OrdType == OrdLimit && len(book.Bids) > 0 && len(book.Asks) > 0 &&
+		((o.Side == "Buy" && o.Price >= book.Asks[0][0]) || (o.Side == "Sell" && o.Price <= book.Bids[0][0]))
+	if crossed && hasFlag(o.Flags, FlagPostOnly) {
+		o.OrdStatus = "Canceled"
+		o.WorkingIndicator = false
+		o.Crossed = true
+		o.Text = "Canceled: post only order would have crossed"
+		m.emit(func() { a.onOrder("", o, false) })
+		return nil
+	}
+
+	m.emit(func() { a.onOrder("", o, false) })
+	if crossed {
+		m.fill(a, o)
+	} else {
+		m.orders[a.Name] = append(m.orders[a.Name], o)
+	}
+	m.emitOrders(a)
+	return nil
+}
+
+func (m *MockExchange) AmendOrder(a *Account, params map[string]interface{}) error {
+	m.Lock()
+	defer m.Unlock()
+
+	i, err := m.find(a, params)
+	if err != nil {
+		return err
+	}
+	o := m.orders[a.Name][i]
+	if v, ok := params["price"].(float64); ok {
+		o.Price = v
+	}
+	if v, ok := params["leavesQty"].(float64); ok {
+		o.LeavesQty = v
+		o.OrderQty = o.CumQty + v
+	}
+	if v, ok := params["orderQty"].(float64); ok {
+		o.OrderQty = v
+		o.LeavesQty = v - o.CumQty
+	}
+	if v, ok := params["clOrdID"].(string); ok && params["origClOrdID"] != nil {
+		o.ClOrdID = v
+	}
+	if o.LeavesQty <= 0 {
+		return fmt.Errorf("invalid amend: %v", params)
+	}
+	o.Timestamp = time.Now().UTC().Format(time.RFC3339Nano)
+	m.orders[a.Name][i] = o
+	m.emit(func() { a.onOrder("New", o, true) })
+	m.emitOrders(a)
+	return nil
+}
+
+func (m *MockExchange) CancelOrder(a *Account, params map[string]interface{}) error {
+	m.Lock()
+	defer m.Unlock()
+
+	i, err := m.find(a, params)
+	if err != nil {
+		return err
+	}
+	o := m.orders[a.Name][i]
+	m.orders[a.Name] = append(m.orders[a.Name][:i:i], m.orders[a.Name][i+1:]...)
+	o.OrdStatus = "Canceled"
+	o.WorkingIndicator = false
+	m.emit(func() { a.onOrder("New", o, false) })
+	m.emitOrders(a)
+	return nil
+}
+
+func (m *MockExchange) SetLeverage(a *Account, symbol string, leverage float64) error {
+	m.Lock()
+	defer m.Unlock()
+
+	positions := m.positions[a.Name]
+	if positions == nil {
+		positions = make(map[string]Position)
+		m.positions[a.Name] = positions
+	}
+	p := positions[symbol]
+	p.Symbol = symbol
+	p.Leverage = leverage
+	positions[symbol] = p
+	m.emit(func() { a.setPosition(p) })
+	return nil
+}
+
+func (m *MockExchange) Orders(a *Account) ([]Order, error) {
+	m.Lock()
+	defer m.Unlock()
+	return append([]Order(nil), m.orders[a.Name]...), nil
+}
+
+func (m *MockExchange) Positions(a *Account) (positions []Position, err error) {
+	m.Lock()
+	defer m.Unlock()
+	for _, p := range m.positions[a.Name] {
+		positions = append(positions, p)
+	}
+	sort.Slice(positions, func(i, j int) bool { return positions[i].Symbol < positions[j].Symbol })
+	return
+}
@@ -0,0 +1,74 @@
+package boot
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMockExchange(t *testing.T) {
+	saved, savedExchange := accounts, exchange
+	defer func() {
+		accounts, exchange = saved, savedExchange
+		orderBook10 = make(map[string]OrderBook10)
+	}()
+
+	m := NewMockExchange()
+	exchange = m
+	a := NewAccount(&AccountConfig{Name: "test"})
+	accounts = []*Account{a}
+	assert.Nil(t, m.Connect(accounts))
+	assert.Nil(t, m.Subscribe([]string{"XBTUSD"}))
+	assert.Nil(t, m.SetLeverage(a, "XBTUSD", 10))
+
+	m.Book(OrderBook10{Symbol: "XBTUSD", Bids: []Bid{{6500, 100}}, Asks: []Ask{{6501, 100}}})
+	m.Drain()
+	assert.Equal(t, 6500.0, orderBook10["XBTUSD"].Bids[0][0])
+	assert.Equal(t, 10.0, a.position["XBTUSD"].Leverage)
+
+	// 挂单 减仓单成交后不挂止盈
+	assert.Nil(t, m.PlaceOrder(a, map[string]interface{}{"symbol": "XBTUSD", "side": "Buy", "orderQty": 100.0, "price": 6500.0, "clOrdID": newClOrdID(PurposeUnwind)}))
+	assert.NotNil(t, m.PlaceOrder(a, map[string]interface{}{"symbol": "XBTUSD", "side": "Buy", "orderQty": 0.0, "price": 6500.0}))
+	m.Drain()
+	assert.Len(t, a.order, 1)
+	orderID := a.order[0].OrderID
+
+	// 被动单穿价被撤
+	assert.Nil(t, m.PlaceOrder(a, map[string]interface{}{"symbol": "XBTUSD", "side": "Buy", "orderQty": 100.0, "price": 6501.0, "flags": []string{FlagPostOnly}}))
+	m.Drain()
+	assert.Len(t, a.order, 1)
+
+	// 改单
+	assert.Nil(t, m.AmendOrder(a, map[string]interface{}{"orderID": orderID, "price": 6499.5}))
+	m.Drain()
+	assert.Equal(t, 6499.5, a.order[0].Price)
+
+	// 成交穿过挂单价
+	m.Trade(Trade{Symbol: "XBTUSD", Side: "Sell", Price: 6499, Size: 500})
+	m.Drain()
+	assert.Empty(t, a.order)
+	assert.Equal(t, 100.0, a.position["XBTUSD"].CurrentQty)
+	s, ok := a.ledger.Get("XBTUSD")
+	assert.True(t, ok)
+	assert.Equal(t, 100.0, s.Turnover)
+
+	positions, err := m.Positions(a)
+	assert.Nil(t, err)
+	assert.Equal(t, []Position{{Symbol: "XBTUSD", Leverage: 10, CurrentQty: 100, Timestamp: positions[0].Timestamp}}, positions)
+
+	// 撤单
+	assert.NotNil(t, m.CancelOrder(a, map[string]interface{}{"orderID": orderID}))
+	assert.Nil(t, m.PlaceOrder(a, map[string]interface{}{"symbol": "XBTUSD", "side": "Sell", "orderQty": 100.0, "price": 6510.0, "clOrdID": "x"}))
+	assert.Nil(t, m.CancelOrder(a, map[string]interface{}{"clOrdID": "x"}))
+	m.Drain()
+	orders, err := m.Orders(a)
+	assert.Nil(t, err)
+	assert.Empty(t, orders)
+	assert.Empty(t, a.order)
+
+	// 连接前从交易所拉取账户状态
+	assert.Nil(t, m.PlaceOrder(a, map[string]interface{}{"symbol": "XBTUSD", "side": "Sell", "orderQty": 100.0, "price": 6510.0, "clOrdID": "y"}))
+	b := newAccount(&AccountConfig{Name: "test"})
+	assert.Nil(t, b.sync())
+	assert.Equal(t, "y", b.order[0].ClOrdID)
+	assert.Equal(t, 100.0, b.position["XBTUSD"].CurrentQty)
+}
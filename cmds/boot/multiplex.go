@@ -0,0 +1,108 @@
+package boot
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"github.com/tidwall/gjson"
+	"strings"
+	"sync"
+)
+
+// /realtimemd 多路复用 一个连接上开多个流 每帧为 [type, id, topic, payload]
+// 公共行情走 MarketStream 每个账户一个流 以账户名为 id 各自认证并订阅私有主题
+
+const (
+	MarketStream = "market"
+
+	streamMessage = 0
+	streamOpen    = 1
+	streamClose   = 2
+)
+
+var (
+	// 需要认证的私有表
+	privateTables = map[string]bool{
+		"execution":            true,
+		"order":                true,
+		"position":             true,
+		"margin":               true,
+		"wallet":               true,
+		"transact":             true,
+		"affiliate":            true,
+		"privateNotifications": true,
+	}
+)
+
+type (
+	// 写连接 多路复用时命令包在流帧中
+	streamConn struct {
+		sync.Mutex
+		conn        *websocket.Conn
+		multiplexed bool
+	}
+)
+
+// multiplexed whether the configured endpoint is /realtimemd
+func multiplexed() bool {
+	return strings.HasSuffix(Conf.WSConfig.Path, "realtimemd")
+}
+
+// splitTopics split topics into public market data and private account topics
+func splitTopics(topics []string) (public, private []string) {
+	for _, v := range topics {
+		if privateTables[strings.SplitN(v, ":", 2)[0]] {
+			private = append(private, v)
+		} else {
+			public = append(public, v)
+		}
+	}
+	return
+}
+
+// dispatchStream unwrap multiplexed frame
+func (b *bitmex) dispatchStream(msg []byte) (err error) {
+	r := gjson.ParseBytes(msg)
+	id := r.Get("1").String()
+	switch r.Get("0").Int() {
+	case streamMessage:
+		return b.route(id, []byte(r.Get("3").Raw))
+	case streamClose:
+		err = fmt.Errorf("stream %s closed: %s", id, r.Get("3").Raw)
+		b.session.fail(err)
+	}
+	return
+}
+
+func (c *streamConn) write(v interface{}) error {
+	msg, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	c.Lock()
+	defer c.Unlock()
+	return c.conn.WriteMessage(websocket.TextMessage, msg)
+}
+
+// open open stream, nothing to do when not multiplexed
+func (c *streamConn) open(stream string) error {
+	if !c.multiplexed {
+		return nil
+	}
+	return c.write([]interface{}{streamOpen, stream, stream})
+}
+
+// send send command on stream
+func (c *streamConn) send(stream string, cmd CMD) error {
+	if !c.multiplexed {
+		return c.write(cmd)
+	}
+	return c.write([]interface{}{streamMessage, stream, stream, cmd})
+}
+
+// ping keep alive, not wrapped in stream
+func (c *streamConn) ping() error {
+	c.Lock()
+	defer c.Unlock()
+	return c.conn.WriteMessage(websocket.TextMessage, []byte("ping"))
+}
@@ -0,0 +1,42 @@
+package boot
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSplitTopics(t *testing.T) {
+	public, private := splitTopics([]string{"orderBook10:XBTUSD", "order", "position", "trade:XBTUSD", "margin"})
+	assert.Equal(t, []string{"orderBook10:XBTUSD", "trade:XBTUSD"}, public)
+	assert.Equal(t, []string{"order", "position", "margin"}, private)
+}
+
+func TestDispatchStream(t *testing.T) {
+	bm := newBitmex().(*bitmex)
+	saved := accounts
+	defer func() {
+		accounts = saved
+	}()
+	main := NewAccount(&AccountConfig{Name: "main"})
+	hedge := NewAccount(&AccountConfig{Name: "hedge"})
+	accounts = []*Account{main, hedge}
+
+	bm.session.Expect(MarketStream, []string{"orderBook10:XBTUSD"}, false)
+	bm.session.Expect("main", []string{"position"}, true)
+	bm.session.Expect("hedge", []string{"position"}, true)
+
+	assert.Nil(t, bm.dispatch([]byte(`[0,"market","market",{"success":true,"subscribe":"orderBook10:XBTUSD","request":{"op":"subscribe","args":["orderBook10:XBTUSD"]}}]`)))
+	assert.Nil(t, bm.dispatch([]byte(`[0,"main","main",{"success":true,"request":{"op":"authKeyExpires","args":[]}}]`)))
+	assert.Nil(t, bm.dispatch([]byte(`[0,"main","main",{"success":true,"subscribe":"position","request":{"op":"subscribe","args":["position"]}}]`)))
+	assert.Equal(t, []string{"hedge position"}, bm.session.Pending())
+	assert.False(t, bm.session.IsReady())
+
+	// 私有表按流分发到对应账户
+	assert.Nil(t, bm.dispatch([]byte(`[0,"hedge","hedge",{"table":"position","action":"partial","keys":["account","symbol","currency"],"data":[{"account":2,"symbol":"XBTUSD","currency":"XBt","currentQty":-100}]}]`)))
+	assert.Equal(t, -100.0, hedge.position["XBTUSD"].CurrentQty)
+	assert.Empty(t, main.position)
+
+	// 流被关闭时会话失败
+	assert.NotNil(t, bm.dispatch([]byte(`[2,"hedge","hedge",{"error":"closed"}]`)))
+	assert.Contains(t, (<-bm.session.Failed()).Error(), "stream hedge closed")
+}
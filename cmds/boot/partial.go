@@ -0,0 +1,120 @@
+package boot
+
+import (
+	"encoding/json"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"sync"
+	"time"
+)
+
+// 每张表最多缓存的增量 一直收不到 partial 时丢弃最早的
+const maxHeld = 1000
+
+type (
+	// 缓存 partial 到达前的增量 按 表 和 symbol 区分
+	// partial 到达后 只应用比 partial 中同一行更新的增量
+	partialBuffer struct {
+		sync.Mutex
+		received map[string]bool // table 或 table:symbol
+		held     map[string][]heldRow
+	}
+
+	heldRow struct {
+		Symbol string
+		Action string
+		Row    json.RawMessage
+	}
+)
+
+func newPartialBuffer() *partialBuffer {
+	return &partialBuffer{
+		received: make(map[string]bool),
+		held:     make(map[string][]heldRow),
+	}
+}
+
+// ready report whether partial of table covering symbol was received
+func (b *partialBuffer) ready(table, symbol string) bool {
+	return b.received[table] || (symbol != "" && b.received[table+":"+symbol])
+}
+
+// handle apply message to handler, deltas before the partial are held back
+func (b *partialBuffer) handle(tm *TableMsg, handler func(*TableMsg) error) (err error) {
+	if tm.Action == "partial" {
+		if err = handler(tm); err != nil {
+			return
+		}
+		return b.release(tm, gjson.GetBytes(tm.Filter, "symbol").String(), handler)
+	}
+
+	b.Lock()
+	var rows []json.RawMessage
+	for _, row := range tm.Data {
+		symbol := gjson.GetBytes(row, "symbol").String()
+		if b.ready(tm.Table, symbol) {
+			rows = append(rows, row)
+			continue
+		}
+		held := append(b.held[tm.Table], heldRow{symbol, tm.Action, row})
+		if len(held) > maxHeld {
+			log.Warnf("%s %s of %s held without partial, dropped", tm.Table, held[0].Action, held[0].Symbol)
+			held = held[1:]
+		}
+		b.held[tm.Table] = held
+		log.Debugf("%s %s of %s held until partial", tm.Table, tm.Action, symbol)
+	}
+	b.Unlock()
+
+	if len(rows) == 0 {
+		return
+	}
+	if len(rows) == len(tm.Data) {
+		return handler(tm)
+	}
+	return handler(&TableMsg{Table: tm.Table, Action: tm.Action, Data: rows})
+}
+
+// release mark partial received and apply held deltas newer than the partial row with the same keys
+func (b *partialBuffer) release(partial *TableMsg, symbol string, handler func(*TableMsg) error) (err error) {
+	key := partial.Table
+	if symbol != "" {
+		key += ":" + symbol
+	}
+
+	keys := partial.Keys
+	if len(keys) == 0 {
+		keys = tableKeys[partial.Table]
+	}
+	since := make(map[string]time.Time)
+	for _, row := range partial.Data {
+		if ts := gjson.GetBytes(row, "timestamp"); ts.Exists() {
+			since[rowKey(keys, row)] = parseTime(ts.String())
+		}
+	}
+
+	b.Lock()
+	b.received[key] = true
+	var release, keep []heldRow
+	for _, v := range b.held[partial.Table] {
+		if b.ready(partial.Table, v.Symbol) {
+			release = append(release, v)
+		} else {
+			keep = append(keep, v)
+		}
+	}
+	b.held[partial.Table] = keep
+	b.Unlock()
+
+	for _, v := range release {
+		t, ok := since[rowKey(keys, v.Row)]
+		if ts := gjson.GetBytes(v.Row, "timestamp"); ts.Exists() && ok && !t.IsZero() && !parseTime(ts.String()).After(t) {
+			log.Debugf("%s %s of %s older than partial, dropped", partial.Table, v.Action, v.Symbol)
+			continue
+		}
+		if err = handler(&TableMsg{Table: partial.Table, Action: v.Action, Data: []json.RawMessage{v.Row}}); err != nil {
+			return
+		}
+	}
+	return
+}
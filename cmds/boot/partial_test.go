@@ -0,0 +1,52 @@
+package boot
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPartialBuffer(t *testing.T) {
+	bm := newBitmex().(*bitmex)
+	saved := accounts
+	defer func() {
+		accounts = saved
+		instruments = make(map[string]Instrument)
+		publicTables = NewTables()
+	}()
+	a := NewAccount(&AccountConfig{Name: DefaultAccount})
+	accounts = []*Account{a}
+
+	// updates before partial are held, stale ones dropped once partial arrives
+	assert.Nil(t, bm.dispatch([]byte(`{"table":"position","action":"update","data":[{"symbol":"XBTUSD","currentQty":100,"timestamp":"2018-10-01T00:00:00.000Z"}]}`)))
+	assert.Nil(t, bm.dispatch([]byte(`{"table":"position","action":"update","data":[{"symbol":"XBTUSD","currentQty":300,"timestamp":"2018-10-01T00:00:02.000Z"}]}`)))
+	assert.Empty(t, a.position)
+
+	assert.Nil(t, bm.dispatch([]byte(`{"table":"position","action":"partial","keys":["account","symbol","currency"],"filter":{"account":1},"data":[{"symbol":"XBTUSD","currentQty":200,"leverage":10,"timestamp":"2018-10-01T00:00:01.000Z"}]}`)))
+	assert.Equal(t, 300.0, a.position["XBTUSD"].CurrentQty)
+	assert.Equal(t, 10.0, a.position["XBTUSD"].Leverage)
+
+	// later updates keep other symbols
+	assert.Nil(t, bm.dispatch([]byte(`{"table":"position","action":"insert","data":[{"symbol":"ETHUSD","currentQty":5,"timestamp":"2018-10-01T00:00:03.000Z"}]}`)))
+	assert.Equal(t, 300.0, a.position["XBTUSD"].CurrentQty)
+	assert.Equal(t, 5.0, a.position["ETHUSD"].CurrentQty)
+
+	// per symbol partial only releases its own symbol
+	assert.Nil(t, bm.dispatch([]byte(`{"table":"instrument","action":"update","data":[{"symbol":"XBTUSD","fundingRate":0.01},{"symbol":"ETHUSD","fundingRate":0.02}]}`)))
+	assert.Nil(t, bm.dispatch([]byte(`{"table":"instrument","action":"partial","filter":{"symbol":"XBTUSD"},"data":[{"symbol":"XBTUSD","tickSize":0.5}]}`)))
+	assert.Equal(t, 0.01, instruments["XBTUSD"].FundingRate)
+	assert.Equal(t, 0.5, instruments["XBTUSD"].TickSize)
+	assert.Len(t, bm.partials.held["instrument"], 1)
+
+	// 增量只与 partial 中同一行的时间比较
+	b := NewAccount(&AccountConfig{Name: DefaultAccount})
+	accounts = []*Account{b}
+	assert.Nil(t, bm.dispatch([]byte(`{"table":"position","action":"update","data":[{"symbol":"XBTUSD","currentQty":300,"timestamp":"2018-10-01T00:00:02.000Z"}]}`)))
+	assert.Nil(t, bm.dispatch([]byte(`{"table":"position","action":"partial","keys":["account","symbol","currency"],"data":[{"symbol":"XBTUSD","currentQty":200,"timestamp":"2018-10-01T00:00:01.000Z"},{"symbol":"ETHUSD","currentQty":5,"timestamp":"2018-10-01T00:00:05.000Z"}]}`)))
+	assert.Equal(t, 300.0, b.position["XBTUSD"].CurrentQty)
+
+	// 收不到 partial 的表缓存有上限
+	for i := 0; i <= maxHeld; i++ {
+		assert.Nil(t, bm.dispatch([]byte(`{"table":"instrument","action":"update","data":[{"symbol":"ETHUSD","fundingRate":0.02}]}`)))
+	}
+	assert.Len(t, bm.partials.held["instrument"], maxHeld)
+}
@@ -0,0 +1,176 @@
+package boot
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"strings"
+)
+
+// 订单用途 记录在 clOrdID 中
+const (
+	PurposeQuote      = "quote"
+	PurposeUnwind     = "unwind"
+	PurposeTakeProfit = "takeprofit"
+	PurposeStopLoss   = "stoploss"
+	PurposeHedge      = "hedge"
+)
+
+// newClOrdID mb-<purpose>-<random>
+func newClOrdID(purpose string) string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "mb-" + purpose + "-" + hex.EncodeToString(b)
+}
+
+// purposeOf purpose of order created by us, empty for others
+func purposeOf(clOrdID string) string {
+	parts := strings.Split(clOrdID, "-")
+	if len(parts) != 3 || parts[0] != "mb" {
+		return ""
+	}
+	return parts[1]
+}
+
+// orderFlags order flags of purpose
+func orderFlags(purpose string) []string {
+	switch purpose {
+	case PurposeQuote:
+		return parseFlags(Conf.ExecInst.Quote)
+	case PurposeUnwind:
+		return parseFlags(Conf.ExecInst.Unwind)
+	case PurposeTakeProfit:
+		return parseFlags(Conf.ExecInst.TakeProfit)
+	case PurposeStopLoss:
+		return parseFlags(Conf.ExecInst.StopLoss)
+	case PurposeHedge:
+		return parseFlags(Conf.ExecInst.Hedge)
+	}
+	return nil
+}
+
+// isPostOnlyCanceled order was canceled because it would have crossed the spread
+func isPostOnlyCanceled(o Order) bool {
+	return o.OrdStatus == "Canceled" && o.Crossed
+}
+
+// isExit stop loss or reduce only order, kept working while the account is halted
+func isExit(clOrdID string, flags []string) bool {
+	return purposeOf(clOrdID) == PurposeStopLoss || hasFlag(flags, FlagReduceOnly) || hasFlag(flags, FlagClose)
+}
+
+// isWorking order is resting in the book
+func isWorking(o Order) bool {
+	return o.OrdStatus == "New" || o.OrdStatus == "PartiallyFilled"
+}
+
+// isTerminal order will not change any more
+func isTerminal(o Order) bool {
+	return o.OrdStatus == "Filled" || o.OrdStatus == "Canceled" || o.OrdStatus == "Rejected"
+}
+
+// workingQuotes working quote orders of symbol
+func workingQuotes(symbol string, orders []Order) (working []Order) {
+	for _, v := range orders {
+		if v.Symbol == symbol && isWorking(v) && purposeOf(v.ClOrdID) == PurposeQuote {
+			working = append(working, v)
+		}
+	}
+	return
+}
+
+// plan diff desired quotes against working orders of one symbol and return the
+// minimum set of operations: orders matching a quote are kept, the rest are paired
+// by side and amended, leftovers are canceled or created
+func plan(symbol string, desired []Quote, working []Order) (ops []Operate) {
+	for _, side := range []string{"Buy", "Sell"} {
+		var quotes []Quote
+		for _, q := range desired {
+			if q.Side == side && q.Qty > 0 {
+				quotes = append(quotes, q)
+			}
+		}
+		var orders []Order
+		for _, o := range working {
+			if o.Side == side {
+				orders = append(orders, o)
+			}
+		}
+
+		// 价格相同的保留 数量不同的改量
+		kept := make([]bool, len(orders))
+		var rest []Quote
+		for _, q := range quotes {
+			matched := false
+			for i, o := range orders {
+				if kept[i] || o.Price != q.Price {
+					continue
+				}
+				kept[i] = true
+				matched = true
+				if o.LeavesQty != q.Qty {
+					ops = append(ops, amendOp(symbol, o, q))
+				}
+				break
+			}
+			if !matched {
+				rest = append(rest, q)
+			}
+		}
+		var stale []Order
+		for i, o := range orders {
+			if !kept[i] {
+				stale = append(stale, o)
+			}
+		}
+
+		// 剩余的按价格从优到劣配对改单
+		better := func(a, b float64) bool {
+			if side == "Buy" {
+				return a > b
+			}
+			return a < b
+		}
+		sort.Slice(rest, func(i, j int) bool { return better(rest[i].Price, rest[j].Price) })
+		sort.Slice(stale, func(i, j int) bool { return better(stale[i].Price, stale[j].Price) })
+
+		n := len(rest)
+		if len(stale) < n {
+			n = len(stale)
+		}
+		for i := 0; i < n; i++ {
+			ops = append(ops, amendOp(symbol, stale[i], rest[i]))
+		}
+		for _, o := range stale[n:] {
+			params := make(map[string]interface{})
+			params["orderID"] = o.OrderID
+			ops = append(ops, Operate{"cancel", symbol, params})
+		}
+		for _, q := range rest[n:] {
+			ops = append(ops, createOp(symbol, q, PurposeQuote))
+		}
+	}
+	return
+}
+
+func amendOp(symbol string, o Order, q Quote) Operate {
+	params := make(map[string]interface{})
+	params["orderID"] = o.OrderID
+	if o.Price != q.Price {
+		params["price"] = q.Price
+	}
+	if o.LeavesQty != q.Qty {
+		params["leavesQty"] = q.Qty
+	}
+	return Operate{"amend", symbol, params}
+}
+
+func createOp(symbol string, q Quote, purpose string) Operate {
+	params := make(map[string]interface{})
+	params["symbol"] = symbol
+	params["side"] = q.Side
+	params["orderQty"] = q.Qty
+	params["price"] = q.Price
+	params["clOrdID"] = newClOrdID(purpose)
+	return Operate{"create", symbol, params}
+}
@@ -0,0 +1,137 @@
+package boot
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestLadder(t *testing.T) {
+	defer func() { Conf = Default() }()
+	Conf.Trading.Range = 3
+	Conf.Quoting.Levels = 3
+	Conf.Quoting.Offset = 0
+	Conf.Quoting.Step = 1
+	Conf.Quoting.Progression = "geometric"
+	Conf.Quoting.SizeStep = 2
+
+	book := OrderBook10{
+		Bids: []Bid{{6500, 1}, {6499.5, 1}, {6499, 1}, {6498.5, 1}},
+		Asks: []Ask{{6501, 1}, {6501.5, 1}, {6502, 1}, {6505, 1}},
+	}
+	q := ladderStrategy{}.Quotes(Snapshot{Book: book})
+	assert.Equal(t, []Quote{
+		{"Buy", 6500, 100}, {"Sell", 6501, 100},
+		{"Buy", 6499.5, 200}, {"Sell", 6501.5, 200},
+		{"Buy", 6499, 400}, {"Sell", 6502, 400},
+	}, q)
+
+	// third level falls out of Range
+	Conf.Quoting.Step = 2
+	Conf.Quoting.Progression = "linear"
+	Conf.Quoting.SizeStep = 0.5
+	q = ladderStrategy{}.Quotes(Snapshot{Book: book})
+	assert.Equal(t, []Quote{
+		{"Buy", 6500, 100}, {"Sell", 6501, 100},
+		{"Buy", 6499, 150}, {"Sell", 6502, 150},
+	}, q)
+
+	// 从公允价两侧起挂
+	Conf.Quoting.Fair = "mid"
+	Conf.Quoting.Step = 1
+	Conf.Quoting.Progression = "flat"
+	Conf.Trading.Spread = 4
+	q = ladderStrategy{}.Quotes(Snapshot{Book: book})
+	assert.Equal(t, []Quote{
+		{"Buy", 6499.5, 100}, {"Sell", 6501.5, 100},
+		{"Buy", 6499, 100}, {"Sell", 6502, 100},
+	}, q)
+}
+
+func TestPlan(t *testing.T) {
+	working := []Order{
+		{OrderID: "a", Side: "Buy", Price: 6500, LeavesQty: 100},
+		{OrderID: "b", Side: "Buy", Price: 6499, LeavesQty: 100},
+		{OrderID: "c", Side: "Buy", Price: 6498, LeavesQty: 100},
+		{OrderID: "d", Side: "Sell", Price: 6502, LeavesQty: 100},
+	}
+	desired := []Quote{
+		{"Buy", 6500.5, 100},
+		{"Buy", 6500, 200},
+		{"Sell", 6501, 100},
+		{"Sell", 6502, 100},
+		{"Sell", 6503, 100},
+	}
+
+	ops := plan("XBTUSD", desired, working)
+	assert.Len(t, ops, 5)
+
+	// a keeps its price and is resized
+	assert.Equal(t, "amend", ops[0].Action)
+	assert.Equal(t, map[string]interface{}{"orderID": "a", "leavesQty": 200.0}, ops[0].Params)
+
+	// b is moved to the new best bid, c is no longer wanted
+	assert.Equal(t, "amend", ops[1].Action)
+	assert.Equal(t, map[string]interface{}{"orderID": "b", "price": 6500.5}, ops[1].Params)
+	assert.Equal(t, "cancel", ops[2].Action)
+	assert.Equal(t, "c", ops[2].Params["orderID"])
+
+	// d is kept, two new asks are created
+	assert.Equal(t, "create", ops[3].Action)
+	assert.Equal(t, 6501.0, ops[3].Params["price"])
+	assert.Equal(t, PurposeQuote, purposeOf(ops[3].Params["clOrdID"].(string)))
+	assert.Equal(t, "create", ops[4].Action)
+	assert.Equal(t, 6503.0, ops[4].Params["price"])
+}
+
+func TestAmendTracker(t *testing.T) {
+	tr := newAmendTracker()
+	now := time.Now()
+	o := Order{OrderID: "a", Side: "Buy", Price: 6500, LeavesQty: 100, OrdStatus: "New"}
+
+	tr.sent(o, map[string]interface{}{"orderID": "a", "price": 6500.5}, now)
+
+	// pending amend is planned as if already applied
+	w := tr.apply([]Order{o}, now.Add(time.Second))
+	assert.Equal(t, 6500.5, w[0].Price)
+	assert.Equal(t, 100.0, w[0].LeavesQty)
+
+	// unrelated update does not ack
+	tr.ack(o)
+	assert.Len(t, tr.pending, 1)
+
+	o.Price = 6500.5
+	tr.ack(o)
+	assert.Len(t, tr.pending, 0)
+
+	// lost amend expires
+	tr.sent(o, map[string]interface{}{"orderID": "a", "leavesQty": 50.0}, now)
+	w = tr.apply([]Order{o}, now.Add(amendTimeout()+time.Second))
+	assert.Equal(t, 100.0, w[0].LeavesQty)
+	assert.Len(t, tr.pending, 0)
+}
+
+func TestAmendFallback(t *testing.T) {
+	saved := exchange
+	defer func() { exchange = saved }()
+	exchange = NewMockExchange()
+
+	a := newAccount(&AccountConfig{Name: "test"})
+	a.order = []Order{{OrderID: "a", ClOrdID: newClOrdID(PurposeQuote), Symbol: "XBTUSD", Side: "Buy", Price: 6500, LeavesQty: 100, OrdStatus: "New"}}
+
+	// 入队时从订单表记录原订单
+	assert.True(t, a.submit(Operate{"amend", "XBTUSD", map[string]interface{}{"orderID": "a", "price": 6500.5}}))
+	assert.Len(t, a.amends.pending, 1)
+	op := <-a.operate
+
+	// 发送队列中改单失败 不读订单表 按记录撤单重下
+	a.order = nil
+	a.amend(op)
+	assert.Empty(t, a.amends.pending)
+	assert.Len(t, a.operate, 2)
+	cancel, create := <-a.operate, <-a.operate
+	assert.Equal(t, Operate{"cancel", "XBTUSD", map[string]interface{}{"orderID": "a"}}, cancel)
+	assert.Equal(t, 6500.5, create.Params["price"])
+	assert.Equal(t, 100.0, create.Params["orderQty"])
+	assert.Equal(t, PurposeQuote, purposeOf(create.Params["clOrdID"].(string)))
+}
@@ -0,0 +1,26 @@
+package boot
+
+import (
+	"math"
+)
+
+type (
+	Quote struct {
+		Side  string
+		Price float64
+		Qty   float64
+	}
+)
+
+// 按价格单位取整
+func roundTick(price, tick float64) float64 {
+	return math.Round(price/tick) * tick
+}
+
+// 持仓比例 -1 ~ 1
+func inventoryRatio(pos Position, maxHold float64) float64 {
+	if maxHold <= 0 {
+		return 0
+	}
+	return math.Max(-1, math.Min(1, pos.CurrentQty/maxHold))
+}
@@ -0,0 +1,33 @@
+package boot
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestQuotesSkew(t *testing.T) {
+	defer func() { Conf = Default() }()
+
+	book := OrderBook10{
+		Symbol: "XBTUSD",
+		Bids:   []Bid{{6500, 100}, {6499.5, 100}},
+		Asks:   []Ask{{6501, 100}, {6501.5, 100}},
+	}
+
+	// top mode ignores inventory
+	q := strategy().Quotes(Snapshot{Book: book, Position: Position{CurrentQty: 500}})
+	assert.Equal(t, []Quote{{"Buy", 6500, 100}, {"Sell", 6501, 100}}, q)
+
+	// half long: shift down one tick, smaller bid, larger ask
+	Conf.Quoting.Mode = "skew"
+	Conf.Quoting.PriceSkew = 2
+	Conf.Quoting.SizeSkew = 0.5
+	q = strategy().Quotes(Snapshot{Book: book, Position: Position{CurrentQty: 500}})
+	assert.Equal(t, []Quote{{"Buy", 6499.5, 75}, {"Sell", 6500.5, 125}}, q)
+
+	// full short: ask can not cross the best bid
+	Conf.Quoting.PriceSkew = 10
+	Conf.Quoting.SizeSkew = 1
+	q = strategy().Quotes(Snapshot{Book: book, Position: Position{CurrentQty: -2000}})
+	assert.Equal(t, []Quote{{"Buy", 6500.5, 200}, {"Sell", 6506, 0}}, q)
+}
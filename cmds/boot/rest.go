@@ -5,21 +5,12 @@ import (
 	log "github.com/sirupsen/logrus"
 	"gopkg.in/resty.v1"
 	"net/http"
+	"net/url"
 	"strconv"
 	"time"
 )
 
 type (
-	Endpoint struct {
-		Verb string
-		Path string
-		*RestConfig
-		*AuthConfig
-		Params   map[string]interface{}
-		Result   interface{}
-		Response *resty.Response
-	}
-
 	OrderResponse struct {
 		OrderID               string  `json:"orderID"`
 		ClOrdID               string  `json:"clOrdID"`
@@ -57,28 +48,52 @@ type (
 	}
 )
 
-func (ep *Endpoint) Do() (err error) {
+// sign api-signature of request
+func (b *bitmex) sign(secret, verb, path string, expires int64, data string) string {
+	return HmacSha256([]byte(secret), []byte(fmt.Sprintf("%s%s%d%s", verb, path, expires, data)))
+}
+
+// do signed REST request of account, result is decoded from the response
+func (b *bitmex) do(a *Account, verb, path string, params map[string]interface{}, result interface{}) (err error) {
+	rc := Conf.RestConfig
+
 	// endpoint
-	endpoint := fmt.Sprintf("%s://%s%s%s", ep.Scheme, ep.Host, ep.Prefix, ep.Path)
-	if ep.Port != "" {
-		endpoint = fmt.Sprintf("%s://%s%s%s%s", ep.Scheme, ep.Host, ":"+ep.Port, ep.Prefix, ep.Path)
+	endpoint := fmt.Sprintf("%s://%s%s%s", rc.Scheme, rc.Host, rc.Prefix, path)
+	if rc.Port != "" {
+		endpoint = fmt.Sprintf("%s://%s%s%s%s", rc.Scheme, rc.Host, ":"+rc.Port, rc.Prefix, path)
+	}
+
+	// GET 参数放在查询串中 签名不含请求体
+	uri, body := rc.Prefix+path, string(mustMarshal(params))
+	if verb == "GET" {
+		if len(params) > 0 {
+			query := url.Values{}
+			for k, v := range params {
+				query.Set(k, fmt.Sprintf("%v", v))
+			}
+			uri += "?" + query.Encode()
+			endpoint += "?" + query.Encode()
+		}
+		body = ""
 	}
 
 	// sign
 	expires := time.Now().Unix() + 5
-	sign := getSign(ep.Secret, ep.Verb, ep.Prefix+ep.Path, expires, string(mustMarshal(ep.Params)))
+	sign := b.sign(a.auth.Secret, verb, uri, expires, body)
 
 	// header
 	header := make(map[string]interface{})
 	header["api-expires"] = expires
-	header["api-key"] = ep.Key
+	header["api-key"] = a.auth.Key
 	header["api-signature"] = sign
 
 	// request instance
 	request := resty.R()
 
 	// body
-	request.SetBody(string(mustMarshal(ep.Params)))
+	if body != "" {
+		request.SetBody(body)
+	}
 
 	// header
 	request.SetHeader("Accept", "application/json")
@@ -88,30 +103,31 @@ func (ep *Endpoint) Do() (err error) {
 	}
 
 	// response
-	if ep.Result != nil {
-		request.SetResult(ep.Result)
+	if result != nil {
+		request.SetResult(result)
 	}
 
 	// do request
-	switch ep.Verb {
+	var resp *resty.Response
+	switch verb {
 	case "POST":
-		ep.Response, err = request.Post(endpoint)
+		resp, err = request.Post(endpoint)
 	case "GET":
-		ep.Response, err = request.Get(endpoint)
+		resp, err = request.Get(endpoint)
 	case "DELETE":
-		ep.Response, err = request.Delete(endpoint)
+		resp, err = request.Delete(endpoint)
 	case "PUT":
-		ep.Response, err = request.Put(endpoint)
+		resp, err = request.Put(endpoint)
 	default:
-		err = fmt.Errorf("verb not supported: %s", ep.Verb)
+		err = fmt.Errorf("verb not supported: %s", verb)
 		return
 	}
 	if err != nil {
 		return
 	}
 
-	respHeader := ep.Response.Header()
-	log.Info(ep.Params)
+	respHeader := resp.Header()
+	log.Info(params)
 	log.Infof("x-ratelimit-remaining: %v", respHeader["X-Ratelimit-Remaining"])
 
 	limit, _ := strconv.ParseInt(respHeader["X-Ratelimit-Remaining"][0], 10, 64)
@@ -120,8 +136,8 @@ func (ep *Endpoint) Do() (err error) {
 	}
 
 	// check status code
-	if ep.Response.StatusCode() != http.StatusOK {
-		err = fmt.Errorf("status code: %v, msg: %s", ep.Response.StatusCode(), string(ep.Response.Body()))
+	if resp.StatusCode() != http.StatusOK {
+		err = fmt.Errorf("status code: %v, msg: %s", resp.StatusCode(), string(resp.Body()))
 		return
 	}
 
@@ -0,0 +1,108 @@
+package boot
+
+import (
+	log "github.com/sirupsen/logrus"
+	"sort"
+)
+
+type (
+	// 单个账户的风险 金额以 XBT 计
+	AccountRisk struct {
+		Name            string
+		Halted          string
+		Net             float64
+		Position        map[string]float64
+		AvailableMargin float64
+	}
+
+	// 所有账户汇总 Position 为各账户净持仓之和
+	RiskView struct {
+		Accounts []AccountRisk
+		Net      float64
+		Position map[string]float64
+	}
+)
+
+// checkRisk whether symbol may keep quoting
+func (a *Account) checkRisk(symbol string) bool {
+	s, ok := a.ledger.Get(symbol)
+	if !ok {
+		return true
+	}
+	log.Infof("%s %s realised: %.8f XBT, fees: %.8f, rebates: %.8f, funding: %.8f, net: %.8f XBT (%s USD), turnover: %v",
+		a.Name, symbol, s.RealisedFifo, s.Fees, s.Rebates, s.Funding, s.Net(), s.usd(s.Net(), "%.2f"), s.Turnover)
+
+	if maxLoss := a.risk().MaxLoss; maxLoss > 0 && s.Net() < -maxLoss {
+		alert("%s %s net loss %.8f XBT exceeds %.8f, stop quoting", a.Name, symbol, -s.Net(), maxLoss)
+		return false
+	}
+	return true
+}
+
+// checkAccountRisk halt account once its net loss over all symbols exceeds Risk.MaxLoss
+func (a *Account) checkAccountRisk() {
+	maxLoss := a.risk().MaxLoss
+	if maxLoss <= 0 {
+		return
+	}
+	if net := a.Risk().Net; net < -maxLoss {
+		a.Halt("net loss exceeds max loss")
+	}
+}
+
+// Risk risk of account
+func (a *Account) Risk() AccountRisk {
+	r := AccountRisk{
+		Name:     a.Name,
+		Halted:   a.Halted(),
+		Position: make(map[string]float64),
+	}
+	for _, s := range a.ledger.Symbols() {
+		r.Net += s.Net()
+	}
+	for k, v := range a.position {
+		if v.CurrentQty != 0 {
+			r.Position[k] = v.CurrentQty
+		}
+	}
+	if m, ok := a.AvailableMargin(Conf.MarginConfig.Currency); ok {
+		r.AvailableMargin = m / Satoshi
+	}
+	return r
+}
+
+// Risks aggregate risk of all accounts
+func Risks() RiskView {
+	view := RiskView{Position: make(map[string]float64)}
+	for _, a := range accounts {
+		r := a.Risk()
+		view.Accounts = append(view.Accounts, r)
+		view.Net += r.Net
+		for k, v := range r.Position {
+			view.Position[k] += v
+		}
+	}
+	return view
+}
+
+// logRisks log aggregate risk
+func logRisks() {
+	view := Risks()
+	for _, r := range view.Accounts {
+		status := "trading"
+		if r.Halted != "" {
+			status = "halted: " + r.Halted
+		}
+		log.Infof("account %s net: %.8f XBT, available margin: %.8f XBT, position: %v, %s", r.Name, r.Net, r.AvailableMargin, r.Position, status)
+	}
+
+	symbols := make([]string, 0, len(view.Position))
+	for k := range view.Position {
+		symbols = append(symbols, k)
+	}
+	sort.Strings(symbols)
+	for _, s := range symbols {
+		log.Infof("total %s position: %v", s, view.Position[s])
+	}
+	log.Infof("total net: %.8f XBT over %d accounts", view.Net, len(view.Accounts))
+}
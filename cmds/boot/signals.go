@@ -0,0 +1,272 @@
+package boot
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+var (
+	signals   map[string]*Signals
+	signalsMu sync.Mutex
+)
+
+type (
+	sample struct {
+		Time  time.Time
+		Value float64
+	}
+
+	tradeSample struct {
+		Time     time.Time
+		Side     string
+		Price    float64
+		Size     float64
+		Distance float64 // 成交价偏离中间价的距离
+	}
+
+	// 行情衍生指标 按时间窗口滚动计算
+	Signals struct {
+		sync.Mutex
+		window       time.Duration
+		mids         []sample // 盘口中间价
+		bookMids     bool     // 有 orderBook10 推送后中间价只取自盘口 不与 quote 交错
+		trades       []tradeSample
+		quote        BookQuote
+		liquidations []Liquidation // 大额强平
+	}
+
+	// 某一时刻的指标值
+	SignalStats struct {
+		Mid             float64       // 中间价
+		Quote           BookQuote     // 最优报价
+		VWAP            float64       // 成交量加权均价
+		Imbalance       float64       // 主动买卖量差 / 总量 -1 ~ 1
+		Volatility      float64       // 中间价波动率 价格 / sqrt(秒)
+		TradeVolatility float64       // 成交价波动率 价格 / sqrt(秒)
+		Intensity       float64       // 成交强度衰减系数 k
+		Liquidations    []Liquidation // 窗口内的大额强平
+	}
+)
+
+func init() {
+	signals = make(map[string]*Signals)
+}
+
+func newSignals(window time.Duration) *Signals {
+	return &Signals{window: window}
+}
+
+// signalsFor get signals of symbol, create if not exists
+func signalsFor(symbol string) *Signals {
+	signalsMu.Lock()
+	defer signalsMu.Unlock()
+	s, ok := signals[symbol]
+	if !ok {
+		s = newSignals(time.Duration(Conf.Quoting.Window) * time.Second)
+		signals[symbol] = s
+	}
+	return s
+}
+
+// 丢弃窗口外的样本
+func trim(samples []sample, now time.Time, window time.Duration) []sample {
+	i := 0
+	for i < len(samples) && now.Sub(samples[i].Time) > window {
+		i++
+	}
+	return samples[i:]
+}
+
+// expire 按查询时间丢弃窗口外的成交和强平 中间价保留最新一个
+func (s *Signals) expire(now time.Time) {
+	i := 0
+	for i < len(s.trades) && now.Sub(s.trades[i].Time) > s.window {
+		i++
+	}
+	s.trades = s.trades[i:]
+
+	i = 0
+	for i < len(s.liquidations) && now.Sub(s.liquidations[i].Time) > s.window {
+		i++
+	}
+	s.liquidations = s.liquidations[i:]
+
+	if n := len(s.mids); n > 0 {
+		if mids := trim(s.mids, now, s.window); len(mids) > 0 {
+			s.mids = mids
+		} else {
+			s.mids = s.mids[n-1:]
+		}
+	}
+}
+
+func (s *Signals) addMid(mid float64, t time.Time) {
+	s.mids = append(trim(s.mids, t, s.window), sample{t, mid})
+}
+
+func (s *Signals) OnBook(book OrderBook10, t time.Time) {
+	if len(book.Bids) == 0 || len(book.Asks) == 0 {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.bookMids = true
+	s.addMid((book.Bids[0][0]+book.Asks[0][0])/2, t)
+}
+
+func (s *Signals) OnQuote(q BookQuote, t time.Time) {
+	if q.BidPrice <= 0 || q.AskPrice <= 0 {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.quote = q
+	if !s.bookMids {
+		s.addMid((q.BidPrice+q.AskPrice)/2, t)
+	}
+}
+
+func (s *Signals) OnTrade(trade Trade, t time.Time) {
+	s.Lock()
+	defer s.Unlock()
+	s.expire(t)
+
+	distance := -1.0
+	if len(s.mids) > 0 {
+		distance = math.Abs(trade.Price - s.mids[len(s.mids)-1].Value)
+	}
+	s.trades = append(s.trades, tradeSample{t, trade.Side, trade.Price, trade.Size, distance})
+}
+
+// OnLiquidation keep liquidations not smaller than Signal.LargeLiquidation, report whether it is one
+func (s *Signals) OnLiquidation(l Liquidation, t time.Time) bool {
+	if l.LeavesQty < Conf.Signal.LargeLiquidation {
+		return false
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.expire(t)
+	l.Time = t
+	s.liquidations = append(s.liquidations, l)
+	return true
+}
+
+// Mid latest mid price
+func (s *Signals) Mid() float64 {
+	s.Lock()
+	defer s.Unlock()
+	if len(s.mids) == 0 {
+		return 0
+	}
+	return s.mids[len(s.mids)-1].Value
+}
+
+// realized variance per second of a series
+func realized(samples []sample) float64 {
+	if len(samples) < 2 {
+		return 0
+	}
+	elapsed := samples[len(samples)-1].Time.Sub(samples[0].Time).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	sum := 0.0
+	for i := 1; i < len(samples); i++ {
+		d := samples[i].Value - samples[i-1].Value
+		sum += d * d
+	}
+	return math.Sqrt(sum / elapsed)
+}
+
+// Volatility realized volatility of mid price, in price per sqrt(second)
+func (s *Signals) Volatility(now time.Time) float64 {
+	s.Lock()
+	defer s.Unlock()
+	s.expire(now)
+	return realized(s.mids)
+}
+
+// TradeVolatility realized volatility of trade price, in price per sqrt(second)
+func (s *Signals) TradeVolatility(now time.Time) float64 {
+	s.Lock()
+	defer s.Unlock()
+	s.expire(now)
+	prices := make([]sample, len(s.trades))
+	for i, v := range s.trades {
+		prices[i] = sample{v.Time, v.Price}
+	}
+	return realized(prices)
+}
+
+// Intensity decay k of order arrival intensity A*exp(-k*δ), estimated as
+// the inverse of the mean distance between trade price and mid
+func (s *Signals) Intensity(now time.Time) float64 {
+	s.Lock()
+	defer s.Unlock()
+	s.expire(now)
+	n, sum := 0, 0.0
+	for _, v := range s.trades {
+		if v.Distance < 0 {
+			continue
+		}
+		n++
+		sum += v.Distance
+	}
+	if sum <= 0 {
+		return 0
+	}
+	return float64(n) / sum
+}
+
+// VWAP volume weighted average trade price
+func (s *Signals) VWAP(now time.Time) float64 {
+	s.Lock()
+	defer s.Unlock()
+	s.expire(now)
+	value, volume := 0.0, 0.0
+	for _, v := range s.trades {
+		value += v.Price * v.Size
+		volume += v.Size
+	}
+	if volume == 0 {
+		return 0
+	}
+	return value / volume
+}
+
+// Imbalance (aggressive buy volume - aggressive sell volume) / total volume
+func (s *Signals) Imbalance(now time.Time) float64 {
+	s.Lock()
+	defer s.Unlock()
+	s.expire(now)
+	buy, sell := 0.0, 0.0
+	for _, v := range s.trades {
+		if v.Side == "Buy" {
+			buy += v.Size
+		} else {
+			sell += v.Size
+		}
+	}
+	if buy+sell == 0 {
+		return 0
+	}
+	return (buy - sell) / (buy + sell)
+}
+
+// Stats values of all signals within window at now
+func (s *Signals) Stats(now time.Time) SignalStats {
+	stats := SignalStats{
+		Mid:             s.Mid(),
+		VWAP:            s.VWAP(now),
+		Imbalance:       s.Imbalance(now),
+		Volatility:      s.Volatility(now),
+		TradeVolatility: s.TradeVolatility(now),
+		Intensity:       s.Intensity(now),
+	}
+	s.Lock()
+	defer s.Unlock()
+	stats.Quote = s.quote
+	stats.Liquidations = append([]Liquidation(nil), s.liquidations...)
+	return stats
+}
@@ -0,0 +1,56 @@
+package boot
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestSignals(t *testing.T) {
+	defer func() { Conf = Default() }()
+	Conf.Signal.LargeLiquidation = 1000
+
+	now := time.Now()
+	s := newSignals(time.Minute)
+	s.OnQuote(BookQuote{Symbol: "XBTUSD", BidPrice: 6500, AskPrice: 6501}, now)
+	s.OnTrade(Trade{Side: "Buy", Price: 6501, Size: 300}, now)
+	s.OnTrade(Trade{Side: "Sell", Price: 6500, Size: 100}, now.Add(time.Second))
+	s.OnQuote(BookQuote{Symbol: "XBTUSD", BidPrice: 6502, AskPrice: 6503}, now.Add(4*time.Second))
+
+	stats := s.Stats(now.Add(4 * time.Second))
+	assert.Equal(t, 6502.5, stats.Mid)
+	assert.Equal(t, 6502.0, stats.Quote.BidPrice)
+	assert.InDelta(t, (6501*300+6500*100)/400.0, stats.VWAP, 1e-9)
+	assert.Equal(t, 0.5, stats.Imbalance)
+	assert.Equal(t, 1.0, stats.Volatility)
+	assert.Equal(t, 1.0, stats.TradeVolatility)
+	assert.Equal(t, 2.0, stats.Intensity)
+
+	// small liquidations are ignored, large ones expire with the window
+	assert.False(t, s.OnLiquidation(Liquidation{Symbol: "XBTUSD", LeavesQty: 10}, now))
+	assert.True(t, s.OnLiquidation(Liquidation{Symbol: "XBTUSD", LeavesQty: 5000}, now))
+	assert.Len(t, s.Stats(now).Liquidations, 1)
+	assert.True(t, s.OnLiquidation(Liquidation{Symbol: "XBTUSD", LeavesQty: 2000}, now.Add(2*time.Minute)))
+	assert.Len(t, s.Stats(now.Add(2*time.Minute)).Liquidations, 1)
+
+	// trades fall out of the window
+	s.OnTrade(Trade{Side: "Sell", Price: 6502, Size: 100}, now.Add(2*time.Minute))
+	assert.Equal(t, -1.0, s.Imbalance(now.Add(2*time.Minute)))
+
+	// 没有新样本时按查询时间过期 最新中间价保留
+	stats = s.Stats(now.Add(4 * time.Minute))
+	assert.Empty(t, stats.Liquidations)
+	assert.Equal(t, 0.0, stats.VWAP)
+	assert.Equal(t, 0.0, stats.Imbalance)
+	assert.Equal(t, 0.0, stats.Intensity)
+	assert.Equal(t, 6502.5, stats.Mid)
+
+	// 有盘口推送后 quote 不再计入中间价序列
+	s = newSignals(time.Minute)
+	s.OnQuote(BookQuote{BidPrice: 6500, AskPrice: 6501}, now)
+	s.OnBook(OrderBook10{Bids: []Bid{{6500, 1}}, Asks: []Ask{{6501, 1}}}, now.Add(time.Second))
+	s.OnQuote(BookQuote{BidPrice: 6510, AskPrice: 6511}, now.Add(2*time.Second))
+	assert.Len(t, s.mids, 2)
+	assert.Equal(t, 6500.5, s.Mid())
+	assert.Equal(t, 6510.0, s.Stats(now.Add(2*time.Second)).Quote.BidPrice)
+}
@@ -0,0 +1,40 @@
+package boot
+
+import (
+	"math"
+)
+
+// 波动率自适应价差 Trading.SpreadMode
+// fixed: 固定 Spread 个价格单位 / mid: 按盘口中间价波动率 / trade: 按成交价波动率
+// 价差 = SpreadFactor * 波动率 * sqrt(SpreadWindow) / PriceUint 限制在 MinSpread ~ MaxSpread 之间
+// 窗口内样本不足时使用 Spread
+
+// spreadTicks quoted and take profit spread in ticks
+func spreadTicks(t *Trading, stats SignalStats) float64 {
+	var sigma float64
+	switch t.SpreadMode {
+	case "mid":
+		sigma = stats.Volatility
+	case "trade":
+		sigma = stats.TradeVolatility
+	default:
+		return t.Spread
+	}
+	if sigma <= 0 || t.PriceUint <= 0 {
+		return t.Spread
+	}
+
+	spread := t.SpreadFactor * sigma * math.Sqrt(float64(t.SpreadWindow)) / t.PriceUint
+	if t.MinSpread > 0 {
+		spread = math.Max(spread, t.MinSpread)
+	}
+	if t.MaxSpread > 0 {
+		spread = math.Min(spread, t.MaxSpread)
+	}
+	return spread
+}
+
+// adaptive spread scales with volatility
+func (t *Trading) adaptive() bool {
+	return t.SpreadMode == "mid" || t.SpreadMode == "trade"
+}
@@ -0,0 +1,36 @@
+package boot
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSpreadTicks(t *testing.T) {
+	defer func() { Conf = Default() }()
+	// 不受 Avellaneda 的 Horizon 影响
+	Conf.Quoting.Horizon = 1
+
+	tr := &Trading{Spread: 2, PriceUint: 0.5, SpreadMode: "fixed", SpreadFactor: 1, SpreadWindow: 100, MinSpread: 1, MaxSpread: 20}
+	assert.Equal(t, 2.0, spreadTicks(tr, SignalStats{Volatility: 0.2}))
+
+	tr.SpreadMode = "mid"
+	// 没有样本时用固定价差
+	assert.Equal(t, 2.0, spreadTicks(tr, SignalStats{}))
+	assert.InDelta(t, 4, spreadTicks(tr, SignalStats{Volatility: 0.2}), 1e-9)
+	assert.Equal(t, 20.0, spreadTicks(tr, SignalStats{Volatility: 2}))
+	assert.Equal(t, 1.0, spreadTicks(tr, SignalStats{Volatility: 0.01}))
+
+	tr.SpreadMode = "trade"
+	assert.InDelta(t, 4, spreadTicks(tr, SignalStats{Volatility: 2, TradeVolatility: 0.2}), 1e-9)
+
+	// 盘口报价 波动大时向外退 平静时贴盘口
+	tr.UnitQty = 100
+	snap := Snapshot{
+		Book:    OrderBook10{Bids: []Bid{{6500, 100}}, Asks: []Ask{{6501, 100}}},
+		Trading: tr,
+		Signals: SignalStats{TradeVolatility: 0.2},
+	}
+	assert.Equal(t, []Quote{{"Buy", 6499.5, 100}, {"Sell", 6501.5, 100}}, topStrategy{}.Quotes(snap))
+	snap.Signals.TradeVolatility = 0.01
+	assert.Equal(t, []Quote{{"Buy", 6500, 100}, {"Sell", 6501, 100}}, topStrategy{}.Quotes(snap))
+}
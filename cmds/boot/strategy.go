@@ -0,0 +1,133 @@
+package boot
+
+import (
+	"math"
+	"time"
+)
+
+var (
+	strategies map[string]Strategy
+)
+
+type (
+	// 报价策略 根据行情快照给出期望挂单
+	Strategy interface {
+		Quotes(snap Snapshot) []Quote
+	}
+
+	// 依赖成交数据的策略 订阅时自动加入成交主题
+	tradeStrategy interface {
+		usesTrades() bool
+	}
+
+	// 行情快照
+	Snapshot struct {
+		Symbol     string
+		Time       time.Time
+		Book       OrderBook10
+		Position   Position
+		Signals    SignalStats
+		Instrument Instrument
+		Index      Instrument // Quoting.Index 指数
+		Funding    NextFunding
+		Trading    *Trading
+		Positions  map[string]Position // 账户所有持仓
+		Basis      BasisStats
+	}
+
+	// 盘口报价
+	topStrategy struct{}
+
+	// 按持仓偏移报价
+	skewStrategy struct{}
+)
+
+func init() {
+	strategies = map[string]Strategy{
+		"top":        topStrategy{},
+		"skew":       skewStrategy{},
+		"avellaneda": avellanedaStrategy{},
+		"ladder":     ladderStrategy{},
+		"basis":      basisStrategy{},
+	}
+}
+
+// strategy get strategy by Quoting.Mode, fall back to top
+func strategy() Strategy {
+	if s, ok := strategies[Conf.Quoting.Mode]; ok {
+		return s
+	}
+	return strategies["top"]
+}
+
+// usesTrades whether quoting of account needs the trade feed
+func (a *Account) usesTrades() bool {
+	if s, ok := strategy().(tradeStrategy); ok && s.usesTrades() {
+		return true
+	}
+	return a.trading().SpreadMode == "trade"
+}
+
+// snapshot build snapshot of symbol from live state of account
+func (a *Account) snapshot(symbol string) Snapshot {
+	f, _ := nextFunding(symbol)
+	now := time.Now()
+	return Snapshot{
+		symbol,
+		now,
+		orderBook10[symbol],
+		a.position[symbol],
+		signalsFor(symbol).Stats(now),
+		instruments[symbol],
+		instruments[Conf.Quoting.Index],
+		f,
+		a.trading(),
+		a.position,
+		basis.Stats(),
+	}
+}
+
+// trading trading parameters of the account quoting, global Trading if not set
+func (s Snapshot) trading() *Trading {
+	if s.Trading == nil {
+		return Conf.Trading
+	}
+	return s.Trading
+}
+
+func (topStrategy) Quotes(snap Snapshot) []Quote {
+	bid, ask, ok := touch(snap)
+	if !ok {
+		return nil
+	}
+	unit := snap.trading().UnitQty
+	return []Quote{
+		{"Buy", bid, unit},
+		{"Sell", ask, unit},
+	}
+}
+
+// 多头时买单放宽变小 卖单收紧变大 空头反之
+func (skewStrategy) Quotes(snap Snapshot) []Quote {
+	book := snap.Book
+	bid, ask, ok := touch(snap)
+	if !ok {
+		return nil
+	}
+	t := snap.trading()
+	ratio := inventoryRatio(snap.Position, t.MaxHoldQty)
+	shift := roundTick(ratio*Conf.Quoting.PriceSkew*t.PriceUint, t.PriceUint)
+
+	return []Quote{
+		{
+			"Buy",
+			math.Min(bid-shift, book.Asks[0][0]-t.PriceUint),
+			math.Max(0, math.Round(t.UnitQty*(1-Conf.Quoting.SizeSkew*ratio))),
+		},
+		{
+			"Sell",
+			math.Max(ask-shift, book.Bids[0][0]+t.PriceUint),
+			math.Max(0, math.Round(t.UnitQty*(1+Conf.Quoting.SizeSkew*ratio))),
+		},
+	}
+}
@@ -0,0 +1,67 @@
+package boot
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func bookFrame(ts string, bid, ask float64) []byte {
+	return []byte(fmt.Sprintf(`{"table":"orderBook10","action":"update","data":[{"symbol":"XBTUSD","bids":[[%v,100]],"asks":[[%v,100]],"timestamp":"%s"}]}`, bid, ask, ts))
+}
+
+func tradeFrame(ts, side string, price, size float64) []byte {
+	return []byte(fmt.Sprintf(`{"table":"trade","action":"insert","data":[{"symbol":"XBTUSD","side":"%s","price":%v,"size":%v,"timestamp":"%s"}]}`, side, price, size, ts))
+}
+
+func TestAvellaneda(t *testing.T) {
+	defer func() { Conf = Default() }()
+	Conf.Quoting.RiskAversion = 0.1
+	Conf.Quoting.Horizon = 60
+
+	book := OrderBook10{
+		Symbol: "XBTUSD",
+		Bids:   []Bid{{6500, 100}},
+		Asks:   []Ask{{6501, 100}},
+	}
+	snap := Snapshot{Book: book}
+
+	// flat inventory: symmetric around mid
+	bid, ask := avellaneda(snap, 0.5, 2)
+	assert.Equal(t, 6499.0, bid)
+	assert.Equal(t, 6502.0, ask)
+
+	// long inventory pulls reservation price down, ask stays passive
+	snap.Position.CurrentQty = 200
+	bid, ask = avellaneda(snap, 0.5, 2)
+	assert.Equal(t, 6496.0, bid)
+	assert.Equal(t, 6500.5, ask)
+
+	// no estimate yet, no quotes
+	snap.Signals = SignalStats{}
+	assert.Nil(t, avellanedaStrategy{}.Quotes(snap))
+}
+
+func TestBacktest(t *testing.T) {
+	defer func() { Conf = Default() }()
+	Conf.Trading.Watch = 1
+
+	bt := newBacktest(strategies["top"])
+	frames := [][]byte{
+		bookFrame("2018-10-01T00:00:00.000Z", 6500, 6501),
+		tradeFrame("2018-10-01T00:00:00.500Z", "Sell", 6500, 60),
+		tradeFrame("2018-10-01T00:00:00.600Z", "Sell", 6500, 60),
+		bookFrame("2018-10-01T00:00:01.000Z", 6509.5, 6510),
+		tradeFrame("2018-10-01T00:00:01.500Z", "Buy", 6510, 100),
+	}
+	for _, f := range frames {
+		assert.Nil(t, bt.feed(f))
+	}
+
+	r := bt.results()
+	assert.Len(t, r, 1)
+	assert.Equal(t, 3, r[0].Fills)
+	assert.Equal(t, 200.0, r[0].Volume)
+	assert.Equal(t, 0.0, r[0].Position)
+	assert.InDelta(t, 100/6500.0-100/6510.0, r[0].Pnl, 1e-12)
+}
@@ -0,0 +1,153 @@
+package boot
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"sort"
+	"sync"
+)
+
+const (
+	SubscribeTimeout = 10
+)
+
+type (
+	// 连接的认证和订阅状态 多路复用时按流区分
+	// 所有流都认证成功且所有主题都确认订阅后 ready 关闭 任一被拒绝时 failed 收到错误
+	Session struct {
+		sync.Mutex
+		auth    map[string]bool // 等待认证的流
+		pending map[string]bool // 等待确认的 流 主题
+		ready   chan struct{}
+		failed  chan error
+		closed  bool
+		topics  bool // 已开始订阅 认证先于订阅确认时不能就绪
+	}
+)
+
+func NewSession() *Session {
+	return &Session{
+		auth:    make(map[string]bool),
+		pending: make(map[string]bool),
+		ready:   make(chan struct{}),
+		failed:  make(chan error, 1),
+	}
+}
+
+func streamTopic(stream, topic string) string {
+	if stream == "" {
+		return topic
+	}
+	return stream + " " + topic
+}
+
+// Expect wait for subscription of topics on stream, and its auth if required
+func (s *Session) Expect(stream string, topics []string, auth bool) {
+	s.Lock()
+	defer s.Unlock()
+	if auth {
+		s.auth[stream] = true
+	}
+	for _, v := range topics {
+		s.pending[streamTopic(stream, v)] = true
+		s.topics = true
+	}
+}
+
+// Ready closed once authenticated and all topics subscribed
+func (s *Session) Ready() <-chan struct{} {
+	return s.ready
+}
+
+// Failed receive auth or subscribe error
+func (s *Session) Failed() <-chan error {
+	return s.failed
+}
+
+// Pending topics not yet confirmed
+func (s *Session) Pending() (topics []string) {
+	s.Lock()
+	defer s.Unlock()
+	for v := range s.pending {
+		topics = append(topics, v)
+	}
+	sort.Strings(topics)
+	return
+}
+
+// IsReady report whether session is ready without blocking
+func (s *Session) IsReady() bool {
+	select {
+	case <-s.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *Session) fail(err error) {
+	select {
+	case s.failed <- err:
+	default:
+	}
+}
+
+func (s *Session) check() {
+	if s.closed || !s.topics || len(s.auth) > 0 || len(s.pending) > 0 {
+		return
+	}
+	s.closed = true
+	close(s.ready)
+}
+
+// isControl message is a response to a command rather than table data
+func isControl(msg []byte) bool {
+	r := gjson.ParseBytes(msg)
+	return !r.Get("table").Exists() && (r.Get("success").Exists() || r.Get("error").Exists() || r.Get("info").Exists())
+}
+
+// 命令响应
+// {"success":true,"request":{"op":"authKeyExpires","args":[...]}}
+// {"success":true,"subscribe":"order","request":{"op":"subscribe","args":["order"]}}
+// {"status":400,"error":"Unknown table: foo","meta":{},"request":{"op":"subscribe","args":["foo"]}}
+func (b *bitmex) handleControl(stream string, msg []byte) (err error) {
+	r := gjson.ParseBytes(msg)
+	if info := r.Get("info"); info.Exists() {
+		log.Info(info.String())
+		return
+	}
+
+	op := r.Get("request.op").String()
+	s := b.session
+	s.Lock()
+	defer s.Unlock()
+
+	if e := r.Get("error"); e.Exists() {
+		switch op {
+		case "authKeyExpires":
+			err = fmt.Errorf("%sauth rejected: %s", streamTopic(stream, ""), e.String())
+		case "subscribe":
+			err = fmt.Errorf("subscribe %s rejected: %s", streamTopic(stream, r.Get("request.args").String()), e.String())
+		default:
+			err = fmt.Errorf("%s%s error: %s", streamTopic(stream, ""), op, e.String())
+		}
+		s.fail(err)
+		return
+	}
+
+	if !r.Get("success").Bool() {
+		return
+	}
+	switch op {
+	case "authKeyExpires":
+		log.Infof("%s authenticated", stream)
+		delete(s.auth, stream)
+	case "subscribe":
+		topic := streamTopic(stream, r.Get("subscribe").String())
+		log.Infof("subscribed %s", topic)
+		delete(s.pending, topic)
+	}
+	s.check()
+	return
+}
@@ -0,0 +1,33 @@
+package boot
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSession(t *testing.T) {
+	bm := newBitmex().(*bitmex)
+	bm.session.Expect("", []string{"order", "orderBook10:XBTUSD"}, true)
+	assert.False(t, isControl([]byte(`{"table":"order","action":"partial","data":[]}`)))
+	assert.True(t, isControl([]byte(`{"info":"Welcome to the BitMEX Realtime API."}`)))
+
+	assert.Nil(t, bm.dispatch([]byte(`{"success":true,"subscribe":"order","request":{"op":"subscribe","args":["order"]}}`)))
+	assert.Nil(t, bm.dispatch([]byte(`{"success":true,"request":{"op":"authKeyExpires","args":["key",1,"sign"]}}`)))
+	assert.False(t, bm.session.IsReady())
+	assert.Equal(t, []string{"orderBook10:XBTUSD"}, bm.session.Pending())
+
+	assert.Nil(t, bm.dispatch([]byte(`{"success":true,"subscribe":"orderBook10:XBTUSD","request":{"op":"subscribe","args":["orderBook10:XBTUSD"]}}`)))
+	assert.True(t, bm.session.IsReady())
+
+	// rejected subscription fails the bm.session
+	bm.session = NewSession()
+	bm.session.Expect("", []string{"foo"}, false)
+	assert.NotNil(t, bm.dispatch([]byte(`{"status":400,"error":"Unknown table: foo","meta":{},"request":{"op":"subscribe","args":["foo"]}}`)))
+	err := <-bm.session.Failed()
+	assert.Contains(t, err.Error(), "Unknown table: foo")
+
+	bm.session = NewSession()
+	bm.session.Expect("", nil, true)
+	assert.NotNil(t, bm.dispatch([]byte(`{"status":401,"error":"Signature not valid.","meta":{},"request":{"op":"authKeyExpires","args":[]}}`)))
+	assert.Contains(t, (<-bm.session.Failed()).Error(), "auth rejected")
+}
@@ -0,0 +1,228 @@
+package boot
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/tidwall/gjson"
+	"strings"
+	"sync"
+)
+
+type (
+	// 实时表消息 keys 和 filter 只在 partial 中出现
+	// 每帧只解码一次 行保留原始 json 由表按类型合并
+	TableMsg struct {
+		Table  string            `json:"table"`
+		Action string            `json:"action"`
+		Keys   []string          `json:"keys,omitempty"`
+		Filter json.RawMessage   `json:"filter,omitempty"`
+		Data   []json.RawMessage `json:"data"`
+	}
+
+	// 一行数据 字段名到原始 json 值 未登记类型的表使用
+	Row map[string]json.RawMessage
+
+	// merge 把增量解码到上一行的副本上 prev 为 nil 时解码为新行
+	// json 只写入出现的字段 零值也会被应用 null 保留原值
+	merge func(prev interface{}, patch json.RawMessage) (interface{}, error)
+
+	// 按 partial 给出的 keys 索引的表 行按表的类型解码 增量按字段是否出现合并
+	Table struct {
+		keys  []string
+		rows  map[string]interface{}
+		index []string // 插入顺序
+		merge merge
+	}
+
+	Tables struct {
+		sync.Mutex
+		tables map[string]*Table
+	}
+)
+
+// 测试数据等 partial 未给出 keys 时使用的主键
+var tableKeys = map[string][]string{
+	"order":      {"orderID"},
+	"position":   {"account", "symbol", "currency"},
+	"instrument": {"symbol"},
+	"margin":     {"account", "currency"},
+	"wallet":     {"account", "currency"},
+}
+
+// 各表行的类型
+var tableRows = map[string]merge{
+	"order": func(prev interface{}, patch json.RawMessage) (interface{}, error) {
+		v, _ := prev.(Order)
+		err := json.Unmarshal(patch, &v)
+		return v, err
+	},
+	"position": func(prev interface{}, patch json.RawMessage) (interface{}, error) {
+		v, _ := prev.(Position)
+		err := json.Unmarshal(patch, &v)
+		return v, err
+	},
+	"instrument": func(prev interface{}, patch json.RawMessage) (interface{}, error) {
+		v, _ := prev.(Instrument)
+		err := json.Unmarshal(patch, &v)
+		return v, err
+	},
+	"margin": func(prev interface{}, patch json.RawMessage) (interface{}, error) {
+		v, _ := prev.(Margin)
+		err := json.Unmarshal(patch, &v)
+		return v, err
+	},
+	"wallet": func(prev interface{}, patch json.RawMessage) (interface{}, error) {
+		v, _ := prev.(Wallet)
+		err := json.Unmarshal(patch, &v)
+		return v, err
+	},
+}
+
+// mergeRow merge patch into a copy of prev field by field
+func mergeRow(prev interface{}, patch json.RawMessage) (interface{}, error) {
+	row := Row{}
+	if p, ok := prev.(Row); ok {
+		for k, v := range p {
+			row[k] = v
+		}
+	}
+	v := Row{}
+	if err := json.Unmarshal(patch, &v); err != nil {
+		return nil, err
+	}
+	for k, f := range v {
+		row[k] = f
+	}
+	return row, nil
+}
+
+// decodeTable decode frame of table
+func decodeTable(msg []byte) (*TableMsg, error) {
+	tm := &TableMsg{}
+	if err := json.Unmarshal(msg, tm); err != nil {
+		return nil, err
+	}
+	return tm, nil
+}
+
+func NewTables() *Tables {
+	return &Tables{tables: make(map[string]*Table)}
+}
+
+// rowKey key of row in json, fields are compared by their raw values
+func rowKey(keys []string, data json.RawMessage) string {
+	values := make([]string, len(keys))
+	for i, k := range keys {
+		values[i] = gjson.GetBytes(data, k).Raw
+	}
+	return strings.Join(values, "|")
+}
+
+func (t *Table) key(data json.RawMessage) string {
+	return rowKey(t.keys, data)
+}
+
+func (t *Table) remove(key string) {
+	delete(t.rows, key)
+	for i, v := range t.index {
+		if v == key {
+			t.index = append(t.index[:i], t.index[i+1:]...)
+			return
+		}
+	}
+}
+
+// Reset clear table and index it by keys
+func (ts *Tables) Reset(table string, keys []string) {
+	ts.Lock()
+	defer ts.Unlock()
+	ts.reset(table, keys)
+}
+
+// Init index table by keys, rows are kept if already indexed by them
+// 按 symbol 订阅的表 每个 symbol 各有一个 partial
+func (ts *Tables) Init(table string, keys []string) {
+	ts.Lock()
+	defer ts.Unlock()
+	if len(keys) == 0 {
+		keys = tableKeys[table]
+	}
+	if t, ok := ts.tables[table]; ok && strings.Join(t.keys, ",") == strings.Join(keys, ",") {
+		return
+	}
+	ts.reset(table, keys)
+}
+
+func (ts *Tables) reset(table string, keys []string) {
+	if len(keys) == 0 {
+		keys = tableKeys[table]
+	}
+	m, ok := tableRows[table]
+	if !ok {
+		m = mergeRow
+	}
+	ts.tables[table] = &Table{
+		keys:  keys,
+		rows:  make(map[string]interface{}),
+		merge: m,
+	}
+}
+
+// Apply apply one row of partial, insert, update or delete, returns the row before and after it
+func (ts *Tables) Apply(table, action string, data json.RawMessage) (prev, row interface{}, err error) {
+	ts.Lock()
+	defer ts.Unlock()
+
+	t, ok := ts.tables[table]
+	if !ok || len(t.keys) == 0 {
+		err = fmt.Errorf("%s %s before partial", table, action)
+		return
+	}
+
+	key := t.key(data)
+	prev = t.rows[key]
+
+	switch action {
+	case "partial", "insert", "update":
+		// partial 是完整记录 替换已有的行
+		base := prev
+		if action == "partial" {
+			base = nil
+		}
+		if row, err = t.merge(base, data); err != nil {
+			return
+		}
+		if prev == nil {
+			t.index = append(t.index, key)
+		}
+		t.rows[key] = row
+	case "delete":
+		t.remove(key)
+	default:
+		err = fmt.Errorf("unknown action %s", action)
+	}
+	return
+}
+
+// Delete remove row with the same keys as data
+func (ts *Tables) Delete(table string, data json.RawMessage) {
+	ts.Lock()
+	defer ts.Unlock()
+	if t, ok := ts.tables[table]; ok {
+		t.remove(t.key(data))
+	}
+}
+
+// Rows rows of table in insertion order
+func (ts *Tables) Rows(table string) (rows []interface{}) {
+	ts.Lock()
+	defer ts.Unlock()
+	t, ok := ts.tables[table]
+	if !ok {
+		return
+	}
+	for _, key := range t.index {
+		rows = append(rows, t.rows[key])
+	}
+	return
+}
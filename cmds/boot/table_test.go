@@ -0,0 +1,97 @@
+package boot
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// tableMsg decode frame of table, panics on invalid json
+func tableMsg(frame string) *TableMsg {
+	tm, err := decodeTable([]byte(frame))
+	if err != nil {
+		panic(err)
+	}
+	return tm
+}
+
+func TestTables(t *testing.T) {
+	ts := NewTables()
+	_, _, err := ts.Apply("order", "update", []byte(`{"orderID":"a"}`))
+	assert.NotNil(t, err)
+
+	ts.Reset("order", []string{"orderID"})
+	_, _, err = ts.Apply("order", "partial", []byte(`{"orderID":"a","price":6500,"workingIndicator":true}`))
+	assert.Nil(t, err)
+	_, _, err = ts.Apply("order", "insert", []byte(`{"orderID":"b","price":6501}`))
+	assert.Nil(t, err)
+
+	// zero values are applied, absent fields are kept
+	prev, row, err := ts.Apply("order", "update", []byte(`{"orderID":"a","workingIndicator":false}`))
+	assert.Nil(t, err)
+	assert.True(t, prev.(Order).WorkingIndicator)
+	assert.False(t, row.(Order).WorkingIndicator)
+	assert.Equal(t, 6500.0, row.(Order).Price)
+
+	_, _, err = ts.Apply("order", "delete", []byte(`{"orderID":"a"}`))
+	assert.Nil(t, err)
+	rows := ts.Rows("order")
+	assert.Len(t, rows, 1)
+	assert.Equal(t, Order{OrderID: "b", Price: 6501}, rows[0])
+
+	// tables without a row type keep raw fields
+	ts.Reset("trade", []string{"trdMatchID"})
+	_, _, err = ts.Apply("trade", "insert", []byte(`{"trdMatchID":"x","price":6500,"size":1}`))
+	assert.Nil(t, err)
+	_, row, err = ts.Apply("trade", "update", []byte(`{"trdMatchID":"x","size":0}`))
+	assert.Nil(t, err)
+	assert.Equal(t, "6500", string(row.(Row)["price"]))
+	assert.Equal(t, "0", string(row.(Row)["size"]))
+}
+
+func TestHandlePositionZero(t *testing.T) {
+	bm := newBitmex().(*bitmex)
+	a := NewAccount(&AccountConfig{Name: "test"})
+	assert.Nil(t, bm.handlePosition(a, tableMsg(`{"table":"position","action":"partial","keys":["account","symbol","currency"],"data":[{"account":1,"symbol":"XBTUSD","currency":"XBt","currentQty":100,"leverage":10}]}`)))
+	assert.Nil(t, bm.handlePosition(a, tableMsg(`{"table":"position","action":"update","data":[{"account":1,"symbol":"XBTUSD","currency":"XBt","currentQty":0}]}`)))
+	assert.Equal(t, 0.0, a.position["XBTUSD"].CurrentQty)
+	assert.Equal(t, 10.0, a.position["XBTUSD"].Leverage)
+}
+
+func TestHandleOrderTerminal(t *testing.T) {
+	bm := newBitmex().(*bitmex)
+	a := newAccount(&AccountConfig{Name: "test"})
+	assert.Nil(t, bm.handleOrder(a, tableMsg(`{"table":"order","action":"partial","keys":["orderID"],"data":[{"orderID":"a","symbol":"XBTUSD","ordStatus":"New"},{"orderID":"b","symbol":"XBTUSD","ordStatus":"New"},{"orderID":"c","symbol":"XBTUSD","ordStatus":"New"}]}`)))
+	assert.Len(t, a.order, 3)
+
+	// 成交 拒绝 撤销的订单都从表中删除
+	assert.Nil(t, bm.handleOrder(a, tableMsg(`{"table":"order","action":"update","data":[{"orderID":"a","ordStatus":"Filled","leavesQty":0,"timestamp":"2018-10-01T00:00:01.000Z"},{"orderID":"b","ordStatus":"Rejected","timestamp":"2018-10-01T00:00:01.000Z"}]}`)))
+	assert.Nil(t, bm.handleOrder(a, tableMsg(`{"table":"order","action":"insert","data":[{"orderID":"d","symbol":"XBTUSD","ordStatus":"Canceled","timestamp":"2018-10-01T00:00:01.000Z"}]}`)))
+	assert.Len(t, a.tables.Rows("order"), 1)
+	assert.Equal(t, "c", a.order[0].OrderID)
+}
+
+func TestHandleInstrumentAndMargin(t *testing.T) {
+	bm := newBitmex().(*bitmex)
+	defer func() {
+		instruments = make(map[string]Instrument)
+		publicTables = NewTables()
+	}()
+	publicTables = NewTables()
+
+	// 每个 symbol 的 partial 只替换自身的记录
+	assert.Nil(t, bm.handleInstrument(tableMsg(`{"table":"instrument","action":"partial","keys":["symbol"],"data":[{"symbol":"XBTUSD","tickSize":0.5,"fundingRate":0.01}]}`)))
+	assert.Nil(t, bm.handleInstrument(tableMsg(`{"table":"instrument","action":"partial","keys":["symbol"],"data":[{"symbol":"ETHUSD","tickSize":0.05}]}`)))
+	assert.Nil(t, bm.handleInstrument(tableMsg(`{"table":"instrument","action":"update","data":[{"symbol":"XBTUSD","fundingRate":0}]}`)))
+	assert.Equal(t, 0.5, instruments["XBTUSD"].TickSize)
+	assert.Equal(t, 0.0, instruments["XBTUSD"].FundingRate)
+	assert.Equal(t, 0.05, instruments["ETHUSD"].TickSize)
+	assert.Nil(t, bm.handleInstrument(tableMsg(`{"table":"instrument","action":"partial","keys":["symbol"],"data":[{"symbol":"XBTUSD","tickSize":1}]}`)))
+	assert.Equal(t, 1.0, instruments["XBTUSD"].TickSize)
+	assert.Equal(t, 0.05, instruments["ETHUSD"].TickSize)
+
+	// 零值也会被应用
+	a := newAccount(&AccountConfig{Name: "test"})
+	assert.Nil(t, bm.handleWallet(a, tableMsg(`{"table":"wallet","action":"partial","keys":["account","currency"],"data":[{"account":1,"currency":"XBt","amount":100,"prevAmount":50}]}`)))
+	assert.Nil(t, bm.handleWallet(a, tableMsg(`{"table":"wallet","action":"update","data":[{"account":1,"currency":"XBt","amount":0}]}`)))
+	assert.Equal(t, Wallet{Account: 1, Currency: "XBt", PrevAmount: 50}, a.wallets["XBt"])
+}
@@ -0,0 +1,197 @@
+package boot
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+type (
+	// 滑动窗口 记录最近一分钟内的请求时间
+	rateWindow struct {
+		stamps []time.Time
+	}
+
+	// 多个账户共用的窗口
+	sharedWindow struct {
+		sync.Mutex
+		rateWindow
+	}
+
+	// 下单/改单/撤单限速 按品种计数 global 由进程内所有账户共用
+	rateLimiter struct {
+		sync.Mutex
+		orders   map[string]*rateWindow
+		messages map[string]*rateWindow
+		global   *sharedWindow
+		paused   map[string]time.Time
+	}
+)
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		orders:   make(map[string]*rateWindow),
+		messages: make(map[string]*rateWindow),
+		global:   &sharedWindow{},
+		paused:   make(map[string]time.Time),
+	}
+}
+
+// shareGlobal count requests of all accounts in one global window
+func shareGlobal(list []*Account) {
+	global := &sharedWindow{}
+	for _, a := range list {
+		a.limiter.global = global
+	}
+}
+
+// prune drop stamps older than one minute
+func (w *rateWindow) prune(now time.Time) {
+	i := 0
+	for i < len(w.stamps) && now.Sub(w.stamps[i]) >= time.Minute {
+		i++
+	}
+	w.stamps = w.stamps[i:]
+}
+
+// exceeded check whether one more request would break the caps, zero means no cap
+func (w *rateWindow) exceeded(now time.Time, perSecond, perMinute int64) bool {
+	w.prune(now)
+	if perMinute > 0 && int64(len(w.stamps)) >= perMinute {
+		return true
+	}
+	if perSecond > 0 {
+		n := int64(0)
+		for i := len(w.stamps) - 1; i >= 0 && now.Sub(w.stamps[i]) < time.Second; i-- {
+			n++
+		}
+		if n >= perSecond {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *rateWindow) add(now time.Time) {
+	w.stamps = append(w.stamps, now)
+}
+
+func (l *rateLimiter) window(m map[string]*rateWindow, symbol string) *rateWindow {
+	w, ok := m[symbol]
+	if !ok {
+		w = &rateWindow{}
+		m[symbol] = w
+	}
+	return w
+}
+
+// allow record op if it fits in the caps, otherwise pause the symbol and return the reason
+func (l *rateLimiter) allow(op Operate, now time.Time) (err error) {
+	l.Lock()
+	defer l.Unlock()
+
+	conf := Conf.Throttle
+
+	// 暂停期间只允许撤单
+	if until, ok := l.paused[op.Symbol]; ok && now.Before(until) && op.Action != "cancel" {
+		return fmt.Errorf("%s paused until %s", op.Symbol, until.Format(time.RFC3339))
+	}
+
+	messages := l.window(l.messages, op.Symbol)
+	orders := l.window(l.orders, op.Symbol)
+	l.global.Lock()
+	defer l.global.Unlock()
+
+	switch {
+	case l.global.exceeded(now, conf.GlobalPerSecond, conf.GlobalPerMinute):
+		err = fmt.Errorf("global message rate exceeded")
+	case messages.exceeded(now, conf.MessagePerSecond, conf.MessagePerMinute):
+		err = fmt.Errorf("%s message rate exceeded", op.Symbol)
+	// 撤单计入订单数 但不因订单数超限被拦截
+	case op.Action != "cancel" && orders.exceeded(now, conf.OrderPerSecond, conf.OrderPerMinute):
+		err = fmt.Errorf("%s order rate exceeded", op.Symbol)
+	}
+	if err != nil {
+		l.paused[op.Symbol] = now.Add(time.Duration(conf.Pause) * time.Second)
+		return
+	}
+
+	l.global.add(now)
+	messages.add(now)
+	orders.add(now)
+	return
+}
+
+// isPaused quoting of symbol is paused by throttle
+func (l *rateLimiter) isPaused(symbol string, now time.Time) bool {
+	l.Lock()
+	defer l.Unlock()
+	until, ok := l.paused[symbol]
+	return ok && now.Before(until)
+}
+
+// 提交操作 保证金检查通过后入队 在事件循环中调用 返回是否入队
+func (a *Account) submit(op Operate) bool {
+	// 先按用途设置标记 减仓单不检查保证金
+	setFlags(op)
+	if err := a.checkMargin(op); err != nil {
+		alert("%s %v dropped: %v", op.Action, op.Params, err)
+		return false
+	}
+	return a.enqueue(op)
+}
+
+// haltExempt exit orders still placed and amended while the account is halted
+func haltExempt(op Operate) bool {
+	clOrdID, _ := op.Params["clOrdID"].(string)
+	if v, ok := op.Params["origClOrdID"].(string); ok {
+		clOrdID = v
+	}
+	return isExit(clOrdID, paramFlags(op.Params)) || purposeOf(clOrdID) == PurposeTakeProfit && op.Action == "amend"
+}
+
+// setFlags 未指定标记的下单按用途设置订单标记
+func setFlags(op Operate) {
+	if op.Action != "create" {
+		return
+	}
+	if _, ok := op.Params["flags"]; ok {
+		return
+	}
+	if clOrdID, ok := op.Params["clOrdID"].(string); ok {
+		if flags := orderFlags(purposeOf(clOrdID)); len(flags) > 0 {
+			op.Params["flags"] = flags
+		}
+	}
+}
+
+// enqueue 按用途设置订单标记 限速检查通过后进入 operate 队列 不阻塞
+func (a *Account) enqueue(op Operate) bool {
+	setFlags(op)
+
+	if reason := a.Halted(); reason != "" && op.Action != "cancel" && !haltExempt(op) {
+		log.Infof("account %s halted, %s %v dropped", a.Name, op.Action, op.Params)
+		return false
+	}
+
+	if err := a.limiter.allow(op, time.Now()); err != nil {
+		alert("%s %v dropped: %v", op.Action, op.Params, err)
+		return false
+	}
+
+	// 先记录改单 发送失败时才能找回原订单
+	if op.Action == "amend" {
+		a.trackAmend(op)
+	}
+	select {
+	case a.operate <- op:
+		return true
+	default:
+		if orderID, ok := op.Params["orderID"].(string); ok && op.Action == "amend" {
+			a.amends.fail(orderID)
+		}
+		alert("%s operate queue full, %s %v dropped", a.Name, op.Action, op.Params)
+		return false
+	}
+}
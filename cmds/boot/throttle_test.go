@@ -0,0 +1,80 @@
+package boot
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	Conf.Throttle = &Throttle{2, 3, 10, 10, 10, 10, 60}
+	defer func() { Conf = Default() }()
+
+	l := newRateLimiter()
+	now := time.Now()
+	create := Operate{"create", "XBTUSD", nil}
+	cancel := Operate{"cancel", "XBTUSD", nil}
+
+	assert.Nil(t, l.allow(create, now))
+	assert.Nil(t, l.allow(create, now))
+	assert.Nil(t, l.allow(cancel, now))
+
+	// third create in one second trips the per second order cap
+	assert.NotNil(t, l.allow(create, now))
+	assert.True(t, l.isPaused("XBTUSD", now))
+	assert.False(t, l.isPaused("ETHUSD", now))
+
+	// cancels still go through while paused, creates do not
+	assert.Nil(t, l.allow(cancel, now.Add(2*time.Second)))
+	assert.NotNil(t, l.allow(create, now.Add(2*time.Second)))
+
+	// pause expired and the orders slid out of the one minute window
+	later := now.Add(61 * time.Second)
+	assert.False(t, l.isPaused("XBTUSD", later))
+	assert.Nil(t, l.allow(create, later))
+
+	// amends and cancels count towards the per minute order cap
+	l = newRateLimiter()
+	amend := Operate{"amend", "XBTUSD", nil}
+	assert.Nil(t, l.allow(create, now))
+	assert.Nil(t, l.allow(cancel, now.Add(2*time.Second)))
+	assert.Nil(t, l.allow(amend, now.Add(4*time.Second)))
+	assert.NotNil(t, l.allow(amend, now.Add(6*time.Second)))
+	assert.True(t, l.isPaused("XBTUSD", now.Add(6*time.Second)))
+
+	// 全局限速由所有账户共用
+	Conf.Throttle = &Throttle{0, 0, 0, 0, 2, 0, 60}
+	a, b := newAccount(&AccountConfig{Name: "a"}), newAccount(&AccountConfig{Name: "b"})
+	shareGlobal([]*Account{a, b})
+	assert.Nil(t, a.limiter.allow(create, now))
+	assert.Nil(t, b.limiter.allow(create, now))
+	assert.NotNil(t, b.limiter.allow(Operate{"create", "ETHUSD", nil}, now))
+}
+
+func TestSubmitFlags(t *testing.T) {
+	defer func() { Conf = Default() }()
+	Conf.ExecInst.Quote = "postOnly"
+	Conf.ExecInst.Unwind = "ReduceOnly, unknown"
+	Conf.ExecInst.Hedge = ""
+	a := newAccount(&AccountConfig{Name: "test"})
+
+	q := Quote{"Buy", 6500, 100}
+	assert.True(t, a.submit(createOp("XBTUSD", q, PurposeQuote)))
+	assert.True(t, a.submit(createOp("XBTUSD", q, PurposeUnwind)))
+	assert.True(t, a.submit(createOp("XBTUSD", q, PurposeHedge)))
+	explicit := createOp("XBTUSD", q, PurposeQuote)
+	explicit.Params["flags"] = []string{FlagClose}
+	assert.True(t, a.submit(explicit))
+	// 不是本程序下的单不设置
+	other := createOp("XBTUSD", q, PurposeQuote)
+	other.Params["clOrdID"] = "manual"
+	assert.True(t, a.submit(other))
+
+	ops := drain(a)
+	assert.Equal(t, []string{FlagPostOnly}, ops[0].Params["flags"])
+	// BitMEX 的写法同样接受 未知的标记丢弃
+	assert.Equal(t, []string{FlagReduceOnly}, ops[1].Params["flags"])
+	assert.Nil(t, ops[2].Params["flags"])
+	assert.Equal(t, []string{FlagClose}, ops[3].Params["flags"])
+	assert.Nil(t, ops[4].Params["flags"])
+}
@@ -5,8 +5,8 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
-	"fmt"
-	//log "github.com/sirupsen/logrus"
+	log "github.com/sirupsen/logrus"
+	"time"
 )
 
 func HmacSha256(secret, message []byte) string {
@@ -15,11 +15,21 @@ func HmacSha256(secret, message []byte) string {
 	return hex.EncodeToString(hash.Sum(nil))
 }
 
-func getSign(secret, verb, path string, expires int64, data string) string {
-	return HmacSha256([]byte(secret), []byte(fmt.Sprintf("%s%s%d%s", verb, path, expires, data)))
-}
-
 func mustMarshal(v interface{}) []byte {
 	b, _ := json.Marshal(v)
 	return b
 }
+
+// alert log something that needs a human to look at
+func alert(format string, args ...interface{}) {
+	log.WithField("alert", true).Errorf(format, args...)
+}
+
+// parseTime parse bitmex timestamp, fall back to now
+func parseTime(s string) time.Time {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Now()
+	}
+	return t
+}
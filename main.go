@@ -27,6 +27,48 @@ func main() {
 					Name:  "config, c",
 					Usage: "load config file",
 				},
+				cli.StringFlag{
+					Name:  "record, r",
+					Usage: "record received frames to file for backtest",
+				},
+			},
+		},
+		{
+			Name:   "backtest",
+			Usage:  "replay recorded frames through strategy",
+			Action: boot.Backtest,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "debug, d",
+					Usage: "debug switch",
+				},
+				cli.StringFlag{
+					Name:  "config, c",
+					Usage: "load config file",
+				},
+				cli.StringFlag{
+					Name:  "file, f",
+					Usage: "recorded frames file",
+				},
+			},
+		},
+		{
+			Name:   "pnl",
+			Usage:  "pnl and fees of executions in journal",
+			Action: boot.Pnl,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "config, c",
+					Usage: "load config file",
+				},
+				cli.StringFlag{
+					Name:  "symbol, s",
+					Usage: "only this symbol",
+				},
+				cli.StringFlag{
+					Name:  "account, a",
+					Usage: "only this account",
+				},
 			},
 		},
 	}